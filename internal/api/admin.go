@@ -0,0 +1,264 @@
+package api
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal-plugin-sync/types"
+	"go.lumeweb.com/portal/middleware"
+	"net/http"
+	"strconv"
+)
+
+// maxDenylistImportSize is the largest denylist body accepted, in bytes.
+const maxDenylistImportSize = 32 << 20
+
+var (
+	errForbidden        = errors.New("admin access required")
+	errInvalidHash      = errors.New("invalid hash")
+	errDenylistTooLarge = fmt.Errorf("denylist exceeds maximum of %d bytes", maxDenylistImportSize)
+)
+
+// adminMiddleware only lets through users listed as sync admins. It must run after the auth middleware.
+func (s *SyncAPI) adminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := middleware.GetUserFromContext(r.Context())
+
+		if !s.sync.IsAdmin(uint64(user)) {
+			_ = httputil.Context(r, w).Error(errForbidden, http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *SyncAPI) deadLetterList(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	letters, err := s.sync.ListDeadLetters(r.Context())
+	if err != nil {
+		_ = ctx.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]DeadLetterResponse, 0, len(letters))
+	for _, letter := range letters {
+		response = append(response, DeadLetterResponse{
+			ID:        letter.ID,
+			UploadID:  letter.UploadID,
+			Hash:      hex.EncodeToString(letter.Hash),
+			Protocol:  letter.Protocol,
+			Attempts:  letter.Attempts,
+			LastError: letter.LastError,
+			UpdatedAt: letter.UpdatedAt,
+		})
+	}
+
+	ctx.Encode(response)
+}
+
+func (s *SyncAPI) deadLetterRequeue(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		_ = ctx.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.sync.RequeueDeadLetter(r.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, types.ErrDeadLetterNotFound) {
+			_ = ctx.Error(err, http.StatusNotFound)
+			return
+		}
+		_ = ctx.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *SyncAPI) denylistList(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	entries, err := s.sync.ListDenied(r.Context())
+	if err != nil {
+		_ = ctx.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]DenylistEntryResponse, 0, len(entries))
+	for _, entry := range entries {
+		response = append(response, DenylistEntryResponse{
+			Hash:      hex.EncodeToString(entry.Hash),
+			Reason:    entry.Reason,
+			CreatedAt: entry.CreatedAt,
+		})
+	}
+
+	ctx.Encode(response)
+}
+
+func (s *SyncAPI) denylistAdd(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	var req DenylistAddRequest
+	err := ctx.Decode(&req)
+	if err != nil {
+		return
+	}
+
+	hash, err := decodeHash(req.Hash)
+	if err != nil {
+		_ = ctx.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.sync.Deny(r.Context(), hash, req.Reason)
+	if err != nil {
+		_ = ctx.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *SyncAPI) denylistImport(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	count, err := s.sync.ImportDenylist(r.Context(), http.MaxBytesReader(w, r.Body, maxDenylistImportSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			_ = ctx.Error(errDenylistTooLarge, http.StatusRequestEntityTooLarge)
+			return
+		}
+		_ = ctx.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	ctx.Encode(DenylistImportResponse{
+		Imported: count,
+	})
+}
+
+func (s *SyncAPI) denylistRemove(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	hash, err := decodeHash(mux.Vars(r)["hash"])
+	if err != nil {
+		_ = ctx.Error(err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.sync.Allow(r.Context(), hash)
+	if err != nil {
+		if errors.Is(err, types.ErrDenylistEntryNotFound) {
+			_ = ctx.Error(err, http.StatusNotFound)
+			return
+		}
+		_ = ctx.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func decodeHash(hashHex string) ([]byte, error) {
+	hash, err := hex.DecodeString(hashHex)
+	if err != nil || len(hash) == 0 || len(hash) > 64 {
+		return nil, errInvalidHash
+	}
+
+	return hash, nil
+}
+
+func (s *SyncAPI) syncNodeList(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	nodes, err := s.sync.ListSyncNodes(r.Context())
+	if err != nil {
+		_ = ctx.Error(err, syncNodeErrorStatus(err))
+		return
+	}
+
+	response := make([]SyncNodeResponse, 0, len(nodes))
+	for _, node := range nodes {
+		response = append(response, SyncNodeResponse{
+			NodeID:    node.NodeID,
+			PublicKey: hex.EncodeToString(node.PublicKey),
+			LeaseTTL:  int64(node.LeaseTTL.Seconds()),
+			Bootstrap: node.Bootstrap,
+			Indexer:   node.Indexer,
+			Self:      node.Self,
+		})
+	}
+
+	ctx.Encode(response)
+}
+
+func (s *SyncAPI) syncNodeRemove(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	err := s.sync.RemoveSyncNode(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		_ = ctx.Error(err, syncNodeErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *SyncAPI) syncNodePush(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	err := s.sync.PushSyncNodes(r.Context())
+	if err != nil {
+		_ = ctx.Error(err, syncNodeErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *SyncAPI) logView(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	view, err := s.sync.LogView(r.Context())
+	if err != nil {
+		_ = ctx.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	signers := make([]LogSignerResponse, 0, len(view.Signers))
+	for _, signer := range view.Signers {
+		signers = append(signers, LogSignerResponse{
+			NodeID:    signer.NodeID,
+			PublicKey: hex.EncodeToString(signer.PublicKey),
+			Live:      signer.Live,
+		})
+	}
+
+	ctx.Encode(LogViewResponse{
+		Name:     view.Name,
+		Key:      hex.EncodeToString(view.Key),
+		Quorum:   view.Quorum,
+		Signers:  signers,
+		Writable: view.Writable,
+	})
+}
+
+func syncNodeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, types.ErrClusterDisabled):
+		return http.StatusConflict
+	case errors.Is(err, types.ErrSyncNodeNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
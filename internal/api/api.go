@@ -3,6 +3,8 @@ package api
 import (
 	_ "embed"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"github.com/gorilla/mux"
 	"go.lumeweb.com/httputil"
 	"go.lumeweb.com/portal-plugin-sync/types"
@@ -11,9 +13,16 @@ import (
 	"go.lumeweb.com/portal/middleware"
 	"go.lumeweb.com/portal/middleware/swagger"
 	"net/http"
+	"strconv"
 )
 
 const subdomain = "sync"
+const maxBatchImportSize = 1000
+
+var (
+	errNoObjects     = errors.New("no objects provided")
+	errBatchTooLarge = fmt.Errorf("batch exceeds maximum of %d objects", maxBatchImportSize)
+)
 
 //go:embed swagger.yaml
 var swagSpec []byte
@@ -69,7 +78,21 @@ func (s *SyncAPI) Routes() (*mux.Router, error) {
 	router := mux.NewRouter()
 
 	router.HandleFunc("/api/log/key", s.logKey).Methods("GET")
+	router.HandleFunc("/api/object/{identifier}", s.objectLookup).Methods("GET")
 	router.HandleFunc("/api/import", s.objectImport).Methods("POST").Use(authMw)
+	router.HandleFunc("/api/import/batch", s.objectImportBatch).Methods("POST").Use(authMw)
+	router.HandleFunc("/api/import", s.importList).Methods("GET").Use(authMw)
+	router.HandleFunc("/api/import/{id}", s.importStatus).Methods("GET").Use(authMw)
+	router.HandleFunc("/api/admin/dead-letters", s.deadLetterList).Methods("GET").Use(authMw, s.adminMiddleware)
+	router.HandleFunc("/api/admin/dead-letters/{id}/requeue", s.deadLetterRequeue).Methods("POST").Use(authMw, s.adminMiddleware)
+	router.HandleFunc("/api/admin/denylist", s.denylistList).Methods("GET").Use(authMw, s.adminMiddleware)
+	router.HandleFunc("/api/admin/denylist", s.denylistAdd).Methods("POST").Use(authMw, s.adminMiddleware)
+	router.HandleFunc("/api/admin/denylist/import", s.denylistImport).Methods("POST").Use(authMw, s.adminMiddleware)
+	router.HandleFunc("/api/admin/denylist/{hash}", s.denylistRemove).Methods("DELETE").Use(authMw, s.adminMiddleware)
+	router.HandleFunc("/api/admin/nodes", s.syncNodeList).Methods("GET").Use(authMw, s.adminMiddleware)
+	router.HandleFunc("/api/admin/nodes/push", s.syncNodePush).Methods("POST").Use(authMw, s.adminMiddleware)
+	router.HandleFunc("/api/admin/nodes/{id}", s.syncNodeRemove).Methods("DELETE").Use(authMw, s.adminMiddleware)
+	router.HandleFunc("/api/admin/view", s.logView).Methods("GET").Use(authMw, s.adminMiddleware)
 
 	return router, nil
 }
@@ -79,7 +102,44 @@ func (s *SyncAPI) logKey(w http.ResponseWriter, r *http.Request) {
 	keyHex := hex.EncodeToString(s.sync.LogKey())
 
 	response := LogKeyResponse{
-		Key: keyHex,
+		Key:          keyHex,
+		DiscoveryKey: hex.EncodeToString(s.sync.DiscoveryKey()),
+	}
+
+	ctx.Encode(response)
+}
+
+func (s *SyncAPI) objectLookup(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	infos, err := s.sync.Lookup(r.Context(), mux.Vars(r)["identifier"])
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrInvalidIdentifier):
+			_ = ctx.Error(err, http.StatusBadRequest)
+		case errors.Is(err, types.ErrObjectNotFound):
+			_ = ctx.Error(err, http.StatusNotFound)
+		default:
+			_ = ctx.Error(err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := ObjectLookupResponse{
+		Objects: make([]ObjectMetaResponse, 0, len(infos)),
+	}
+
+	for _, info := range infos {
+		response.Objects = append(response.Objects, ObjectMetaResponse{
+			Hash:      hex.EncodeToString(info.Hash),
+			Protocol:  info.Protocol,
+			Size:      info.Size,
+			SlabCount: info.SlabCount,
+			Health:    info.Health,
+			MinShards: info.MinShards,
+			Shards:    info.Shards,
+			Aliases:   info.Aliases,
+		})
 	}
 
 	ctx.Encode(response)
@@ -96,13 +156,138 @@ func (s *SyncAPI) objectImport(w http.ResponseWriter, r *http.Request) {
 
 	user := middleware.GetUserFromContext(r.Context())
 
-	err = s.sync.Import(req.Object, uint64(user))
+	if req.DryRun {
+		estimate, err := s.sync.EstimateImport(r.Context(), req.Object, uint64(user))
+		if err != nil {
+			_ = ctx.Error(err, importErrorStatus(err))
+			return
+		}
+
+		ctx.Encode(ObjectImportEstimateResponse{
+			Object:        estimate.Object,
+			Hash:          hex.EncodeToString(estimate.Hash),
+			Protocol:      estimate.Protocol,
+			Size:          estimate.Size,
+			DownloadBytes: estimate.DownloadBytes,
+			StorageBytes:  estimate.StorageBytes,
+		})
+		return
+	}
+
+	id, err := s.sync.Import(r.Context(), req.Object, uint64(user))
+	if err != nil {
+		_ = ctx.Error(err, importErrorStatus(err))
+		return
+	}
+
+	ctx.Encode(ObjectImportResponse{
+		ID: id,
+	})
+}
+
+func importErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, types.ErrObjectDenied):
+		return http.StatusUnavailableForLegalReasons
+	case errors.Is(err, types.ErrImportQuotaExceeded):
+		return http.StatusTooManyRequests
+	case errors.Is(err, types.ErrObjectTooLarge):
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func (s *SyncAPI) objectImportBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	var req ObjectImportBatchRequest
+	err := ctx.Decode(&req)
+	if err != nil {
+		return
+	}
+
+	if len(req.Objects) == 0 {
+		_ = ctx.Error(errNoObjects, http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Objects) > maxBatchImportSize {
+		_ = ctx.Error(errBatchTooLarge, http.StatusBadRequest)
+		return
+	}
+
+	user := middleware.GetUserFromContext(r.Context())
+
+	results, err := s.sync.ImportBatch(r.Context(), req.Objects, uint64(user))
+	if err != nil {
+		_ = ctx.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	response := ObjectImportBatchResponse{
+		Results: make([]ObjectImportBatchResult, 0, len(results)),
+	}
+
+	for _, result := range results {
+		response.Results = append(response.Results, ObjectImportBatchResult{
+			Object:   result.Object,
+			Accepted: result.Accepted(),
+			ID:       result.ImportID,
+			Reason:   result.Reason,
+			Error:    result.Error,
+		})
+	}
+
+	ctx.Encode(response)
+}
+
+func (s *SyncAPI) importStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
 	if err != nil {
 		_ = ctx.Error(err, http.StatusBadRequest)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	user := middleware.GetUserFromContext(r.Context())
+
+	info, err := s.sync.GetImport(r.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, types.ErrImportNotFound) {
+			_ = ctx.Error(err, http.StatusNotFound)
+			return
+		}
+		_ = ctx.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	if info.UserID != uint(user) {
+		_ = ctx.Error(types.ErrImportNotFound, http.StatusNotFound)
+		return
+	}
+
+	ctx.Encode(newImportResponse(*info))
+}
+
+func (s *SyncAPI) importList(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	user := middleware.GetUserFromContext(r.Context())
+
+	imports, err := s.sync.ListImports(r.Context(), uint64(user))
+	if err != nil {
+		_ = ctx.Error(err, http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]ImportResponse, 0, len(imports))
+	for _, info := range imports {
+		response = append(response, newImportResponse(info))
+	}
+
+	ctx.Encode(response)
 }
 
 func (s *SyncAPI) Configure(router *mux.Router) error {
@@ -119,7 +304,21 @@ func (s *SyncAPI) Configure(router *mux.Router) error {
 	authMw := middleware.AuthMiddleware(authMiddlewareOpts)
 
 	router.HandleFunc("/api/log/key", s.logKey).Methods("GET")
+	router.HandleFunc("/api/object/{identifier}", s.objectLookup).Methods("GET")
 	router.HandleFunc("/api/import", s.objectImport).Methods("POST").Use(authMw)
+	router.HandleFunc("/api/import/batch", s.objectImportBatch).Methods("POST").Use(authMw)
+	router.HandleFunc("/api/import", s.importList).Methods("GET").Use(authMw)
+	router.HandleFunc("/api/import/{id}", s.importStatus).Methods("GET").Use(authMw)
+	router.HandleFunc("/api/admin/dead-letters", s.deadLetterList).Methods("GET").Use(authMw, s.adminMiddleware)
+	router.HandleFunc("/api/admin/dead-letters/{id}/requeue", s.deadLetterRequeue).Methods("POST").Use(authMw, s.adminMiddleware)
+	router.HandleFunc("/api/admin/denylist", s.denylistList).Methods("GET").Use(authMw, s.adminMiddleware)
+	router.HandleFunc("/api/admin/denylist", s.denylistAdd).Methods("POST").Use(authMw, s.adminMiddleware)
+	router.HandleFunc("/api/admin/denylist/import", s.denylistImport).Methods("POST").Use(authMw, s.adminMiddleware)
+	router.HandleFunc("/api/admin/denylist/{hash}", s.denylistRemove).Methods("DELETE").Use(authMw, s.adminMiddleware)
+	router.HandleFunc("/api/admin/nodes", s.syncNodeList).Methods("GET").Use(authMw, s.adminMiddleware)
+	router.HandleFunc("/api/admin/nodes/push", s.syncNodePush).Methods("POST").Use(authMw, s.adminMiddleware)
+	router.HandleFunc("/api/admin/nodes/{id}", s.syncNodeRemove).Methods("DELETE").Use(authMw, s.adminMiddleware)
+	router.HandleFunc("/api/admin/view", s.logView).Methods("GET").Use(authMw, s.adminMiddleware)
 
 	return nil
 }
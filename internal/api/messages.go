@@ -1,9 +1,137 @@
 package api
 
+import (
+	"encoding/hex"
+	"go.lumeweb.com/portal-plugin-sync/types"
+	"time"
+)
+
 type LogKeyResponse struct {
-	Key string `json:"key"`
+	Key          string `json:"key"`
+	DiscoveryKey string `json:"discovery_key"`
+}
+
+type ObjectMetaResponse struct {
+	Hash      string   `json:"hash"`
+	Protocol  string   `json:"protocol"`
+	Size      uint64   `json:"size"`
+	SlabCount int      `json:"slab_count"`
+	Health    float64  `json:"health"`
+	MinShards uint8    `json:"min_shards"`
+	Shards    int      `json:"shards"`
+	Aliases   []string `json:"aliases"`
+}
+
+type ObjectLookupResponse struct {
+	Objects []ObjectMetaResponse `json:"objects"`
 }
 
 type ObjectImportRequest struct {
 	Object string `json:"object"`
+	// DryRun checks the import and returns an estimate of its cost instead of queuing it.
+	DryRun bool `json:"dry_run"`
+}
+
+type ObjectImportEstimateResponse struct {
+	Object        string `json:"object"`
+	Hash          string `json:"hash"`
+	Protocol      string `json:"protocol"`
+	Size          uint64 `json:"size"`
+	DownloadBytes uint64 `json:"download_bytes"`
+	StorageBytes  uint64 `json:"storage_bytes"`
+}
+
+type ObjectImportResponse struct {
+	ID uint `json:"id"`
+}
+
+type ObjectImportBatchRequest struct {
+	Objects []string `json:"objects"`
+}
+
+type ObjectImportBatchResult struct {
+	Object   string                   `json:"object"`
+	Accepted bool                     `json:"accepted"`
+	ID       uint                     `json:"id,omitempty"`
+	Reason   types.ImportRejectReason `json:"reason,omitempty"`
+	Error    string                   `json:"error,omitempty"`
+}
+
+type ObjectImportBatchResponse struct {
+	Results []ObjectImportBatchResult `json:"results"`
+}
+
+type ImportResponse struct {
+	ID        uint               `json:"id"`
+	Object    string             `json:"object"`
+	Hash      string             `json:"hash"`
+	Protocol  string             `json:"protocol"`
+	Size      uint64             `json:"size"`
+	Status    types.ImportStatus `json:"status"`
+	Error     string             `json:"error,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+func newImportResponse(info types.ImportInfo) ImportResponse {
+	return ImportResponse{
+		ID:        info.ID,
+		Object:    info.Object,
+		Hash:      hex.EncodeToString(info.Hash),
+		Protocol:  info.Protocol,
+		Size:      info.Size,
+		Status:    info.Status,
+		Error:     info.Error,
+		CreatedAt: info.CreatedAt,
+		UpdatedAt: info.UpdatedAt,
+	}
+}
+
+type DeadLetterResponse struct {
+	ID        uint      `json:"id"`
+	UploadID  uint      `json:"upload_id"`
+	Hash      string    `json:"hash"`
+	Protocol  string    `json:"protocol"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type DenylistEntryResponse struct {
+	Hash      string    `json:"hash"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DenylistAddRequest struct {
+	Hash   string `json:"hash"`
+	Reason string `json:"reason"`
+}
+
+type DenylistImportResponse struct {
+	Imported int `json:"imported"`
+}
+
+type SyncNodeResponse struct {
+	NodeID    string `json:"node_id"`
+	PublicKey string `json:"public_key"`
+	// LeaseTTL is the number of seconds left on the node's registration.
+	LeaseTTL  int64 `json:"lease_ttl"`
+	Bootstrap bool  `json:"bootstrap"`
+	Indexer   bool  `json:"indexer"`
+	Self      bool  `json:"self"`
+}
+
+type LogViewResponse struct {
+	Name     string              `json:"name"`
+	Key      string              `json:"key"`
+	Quorum   int                 `json:"quorum"`
+	Signers  []LogSignerResponse `json:"signers"`
+	Writable bool                `json:"writable"`
+}
+
+type LogSignerResponse struct {
+	NodeID    string `json:"node_id,omitempty"`
+	PublicKey string `json:"public_key"`
+	Live      bool   `json:"live"`
 }
@@ -15,7 +15,10 @@ type Cron struct {
 func (c Cron) RegisterTasks(crn core.CronService) error {
 	crn.RegisterTask(define.CronTaskVerifyObjectName, tasks.CronTaskVerifyObject, core.CronTaskDefinitionOneTimeJob, define.CronTaskVerifyObjectArgsFactory)
 	crn.RegisterTask(define.CronTaskUploadObjectName, tasks.CronTaskUploadObject, core.CronTaskDefinitionOneTimeJob, define.CronTaskUploadObjectArgsFactory)
+	crn.RegisterTask(define.CronTaskVerifyUploadObjectName, tasks.CronTaskVerifyUploadObject, core.CronTaskDefinitionOneTimeJob, define.CronTaskVerifyObjectArgsFactory)
 	crn.RegisterTask(define.CronTaskScanObjectsName, tasks.CronTaskScanObjects, define.CronTaskScanObjectsDefinition, core.CronTaskNoArgsFactory)
+	crn.RegisterTask(define.CronTaskRetryPublishName, tasks.CronTaskRetryPublish, define.CronTaskRetryPublishDefinition, core.CronTaskNoArgsFactory)
+	crn.RegisterTask(define.CronTaskRemoveDeletedName, tasks.CronTaskRemoveDeleted, define.CronTaskRemoveDeletedDefinition, core.CronTaskNoArgsFactory)
 	return nil
 }
 
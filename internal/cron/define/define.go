@@ -3,16 +3,41 @@ package define
 import (
 	"github.com/go-co-op/gocron/v2"
 	"go.lumeweb.com/portal-plugin-sync/internal/metadata"
+	"time"
 )
 
 const CronTaskVerifyObjectName = "SyncVerifyObject"
 const CronTaskUploadObjectName = "SyncUploadObject"
+const CronTaskVerifyUploadObjectName = "SyncVerifyUploadObject"
 const CronTaskScanObjectsName = "SyncScanObjects"
+const CronTaskRetryPublishName = "SyncRetryPublish"
+const CronTaskRemoveDeletedName = "SyncRemoveDeleted"
+
+// CronTaskScanObjectsFullRescanInterval is how often the scan ignores its cursor and rechecks every upload for changed slabs.
+const CronTaskScanObjectsFullRescanInterval = 7 * 24 * time.Hour
+
+// CronTaskScanObjectsPageSize is how many uploads the scan reads from the database at a time.
+const CronTaskScanObjectsPageSize = 500
+
+// ScanObjectsConfig tunes CronTaskScanObjects. Rates are calls per second, where zero means unlimited.
+type ScanObjectsConfig struct {
+	Workers          int           `mapstructure:"workers"`
+	MetadataRate     float64       `mapstructure:"metadata_rate"`
+	ProofRate        float64       `mapstructure:"proof_rate"`
+	UpdateRate       float64       `mapstructure:"update_rate"`
+	ProgressInterval time.Duration `mapstructure:"progress_interval"`
+}
+
+// ScanObjectsConfigurer is implemented by the service config so the scan task can read its settings.
+type ScanObjectsConfigurer interface {
+	ScanObjectsConfig() ScanObjectsConfig
+}
 
 type CronTaskVerifyObjectArgs struct {
 	Hash       []byte              `json:"hash"`
 	Object     []metadata.FileMeta `json:"object"`
 	UploaderID uint64              `json:"uploader_id"`
+	ImportID   uint                `json:"import_id"`
 }
 
 type CronTaskUploadObjectArgs struct {
@@ -20,6 +45,7 @@ type CronTaskUploadObjectArgs struct {
 	Protocol   string `json:"protocol"`
 	Size       uint64 `json:"size"`
 	UploaderID uint64 `json:"uploader_id"`
+	ImportID   uint   `json:"import_id"`
 }
 
 func CronTaskVerifyObjectArgsFactory() any {
@@ -33,3 +59,11 @@ func CronTaskUploadObjectArgsFactory() any {
 func CronTaskScanObjectsDefinition() gocron.JobDefinition {
 	return gocron.DailyJob(1, gocron.NewAtTimes(gocron.NewAtTime(0, 0, 0)))
 }
+
+func CronTaskRetryPublishDefinition() gocron.JobDefinition {
+	return gocron.DurationJob(time.Minute)
+}
+
+func CronTaskRemoveDeletedDefinition() gocron.JobDefinition {
+	return gocron.DurationJob(5 * time.Minute)
+}
@@ -2,9 +2,11 @@ package tasks
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"errors"
 	"go.lumeweb.com/portal-plugin-sync/internal/cron/define"
+	"go.lumeweb.com/portal-plugin-sync/internal/db"
 	"go.lumeweb.com/portal-plugin-sync/internal/metadata"
 	"go.lumeweb.com/portal-plugin-sync/types"
 	"go.lumeweb.com/portal/bao"
@@ -14,10 +16,20 @@ import (
 	"go.sia.tech/renterd/object"
 	"go.uber.org/zap"
 	"io"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const syncBucketName = "sync"
 
+var (
+	errObjectNotVerified  = errors.New("no candidate object could be verified")
+	errUploadHashMismatch = errors.New("uploaded object hash does not match")
+	errInvalidWhence      = errors.New("invalid whence")
+	errNegativePosition   = errors.New("negative position")
+)
+
 func getSyncProtocol(protocol string) (types.SyncProtocol, error) {
 	proto := core.GetProtocol(protocol)
 
@@ -43,15 +55,30 @@ func encodeProtocolFileName(hash []byte, protocol string) (string, error) {
 	return syncProto.EncodeFileName(hash), nil
 }
 
-func CronTaskVerifyObject(input any, ctx core.Context) error {
+func setImportStatus(ctx core.Context, id uint, status types.ImportStatus, cause error) {
+	err := db.UpdateImportStatus(ctx, ctx.DB(), id, status, cause)
+	if err != nil {
+		ctx.Logger().Error("failed to update import status", zap.Uint("import", id), zap.Error(err))
+	}
+}
+
+func CronTaskVerifyObject(input any, ctx core.Context) (err error) {
 	args, ok := input.(*define.CronTaskVerifyObjectArgs)
 	if !ok {
 		return errors.New("invalid arguments type")
 	}
+
+	setImportStatus(ctx, args.ImportID, types.ImportStatusVerifying, nil)
+	defer func() {
+		if err != nil {
+			setImportStatus(ctx, args.ImportID, types.ImportStatusFailed, err)
+		}
+	}()
+
 	logger := ctx.Logger()
 	renter := ctx.Service(core.RENTER_SERVICE).(core.RenterService)
 	cron := ctx.Service(core.CRON_SERVICE).(core.CronService)
-	err := renter.CreateBucketIfNotExists(syncBucketName)
+	err = renter.CreateBucketIfNotExists(syncBucketName)
 	if err != nil {
 		return err
 	}
@@ -101,81 +128,273 @@ func CronTaskVerifyObject(input any, ctx core.Context) error {
 
 		success = true
 		foundObject = object_
+		break
 	}
 
-	if success {
-		err := cron.CreateJobIfNotExists(define.CronTaskUploadObjectName, define.CronTaskUploadObjectArgs{
-			Hash:       args.Hash,
-			Protocol:   foundObject.Protocol,
-			Size:       foundObject.Size,
-			UploaderID: args.UploaderID,
-		}, []string{hex.EncodeToString(args.Hash)})
-		if err != nil {
-			return err
-		}
+	if !success {
+		setImportStatus(ctx, args.ImportID, types.ImportStatusFailed, errObjectNotVerified)
+		return nil
+	}
+
+	setImportStatus(ctx, args.ImportID, types.ImportStatusVerified, nil)
+
+	err = cron.CreateJobIfNotExists(define.CronTaskUploadObjectName, define.CronTaskUploadObjectArgs{
+		Hash:       args.Hash,
+		Protocol:   foundObject.Protocol,
+		Size:       foundObject.Size,
+		UploaderID: args.UploaderID,
+		ImportID:   args.ImportID,
+	}, []string{hex.EncodeToString(args.Hash)})
+	if err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// seekableSiaStream exposes an object in the sync bucket as an io.ReadSeeker. Seeking reopens the download at the new offset using a ranged renter request.
 type seekableSiaStream struct {
-	rc    io.ReadCloser
-	ctx   core.Context
-	args  *define.CronTaskUploadObjectArgs
-	pos   int64
-	reset bool
-	size  int64
+	rc        io.ReadCloser
+	ctx       core.Context
+	args      *define.CronTaskUploadObjectArgs
+	pos       int64
+	reset     bool
+	size      int64
+	verify    *bao.Result
+	fromStart bool
+	verified  bool
 }
 
-func (r *seekableSiaStream) Read(p []byte) (n int, err error) {
-	if r.reset {
-		r.reset = false
-		err := r.rc.Close()
-		if err != nil {
-			return 0, err
-		}
+type verifiedReadCloser struct {
+	io.Reader
+	io.Closer
+}
 
-		fileName, err := encodeProtocolFileName(r.args.Hash, r.args.Protocol)
-		if err != nil {
-			r.ctx.Logger().Error("failed to encode protocol file name", zap.Error(err))
-			return 0, err
+// wrap runs content through a bao verifier when the stream was opened with a proof, so reads fail as soon as the data does not match it.
+func (r *seekableSiaStream) wrap(content io.ReadCloser) io.ReadCloser {
+	if r.verify == nil {
+		return content
+	}
+
+	return verifiedReadCloser{
+		Reader: bao.NewVerifier(content, *r.verify, r.ctx.Logger().Logger),
+		Closer: content,
+	}
+}
+
+// reopen replaces the current download with one starting at pos.
+func (r *seekableSiaStream) reopen() error {
+	err := r.rc.Close()
+	if err != nil {
+		return err
+	}
+
+	r.fromStart = r.pos == 0
+
+	if r.pos >= r.size {
+		r.rc = io.NopCloser(bytes.NewReader(nil))
+		return nil
+	}
+
+	fileName, err := encodeProtocolFileName(r.args.Hash, r.args.Protocol)
+	if err != nil {
+		r.ctx.Logger().Error("failed to encode protocol file name", zap.Error(err))
+		return err
+	}
+
+	opts := api.DownloadObjectOptions{}
+	if r.pos > 0 {
+		opts.Range = api.DownloadRange{
+			Offset: r.pos,
+			Length: r.size - r.pos,
 		}
+	}
+
+	objectRet, err := r.ctx.Service(core.RENTER_SERVICE).(core.RenterService).GetObject(r.ctx, syncBucketName, fileName, opts)
+	if err != nil {
+		return err
+	}
 
-		objectRet, err := r.ctx.Service(core.RENTER_SERVICE).(core.RenterService).GetObject(r.ctx, syncBucketName, fileName, api.DownloadObjectOptions{})
+	if r.fromStart {
+		r.rc = r.wrap(objectRet.Content)
+	} else {
+		r.rc = objectRet.Content
+	}
+
+	return nil
+}
+
+func (r *seekableSiaStream) Read(p []byte) (n int, err error) {
+	if r.reset {
+		r.reset = false
+		err := r.reopen()
 		if err != nil {
 			return 0, err
 		}
-		r.rc = objectRet.Content
-		r.pos = 0
 	}
 	n, err = r.rc.Read(p)
 	r.pos += int64(n)
+
+	if err == io.EOF && r.verify != nil && r.fromStart && r.pos == r.size {
+		r.verified = true
+	}
+
 	return n, err
 }
 
 func (r *seekableSiaStream) Seek(offset int64, whence int) (int64, error) {
-	if offset == 0 && whence == io.SeekStart {
-		r.reset = true
-		return 0, nil
+	var target int64
+
+	switch whence {
+	case io.SeekStart:
+		target = offset
+	case io.SeekCurrent:
+		target = r.pos + offset
+	case io.SeekEnd:
+		target = r.size + offset
+	default:
+		return 0, errInvalidWhence
+	}
+
+	if target < 0 {
+		return 0, errNegativePosition
+	}
+
+	if target == r.pos && !r.reset {
+		return target, nil
+	}
+
+	r.pos = target
+	r.reset = true
+
+	return target, nil
+}
+
+// finishVerify reads the rest of the object through the verifier. If the current download did not start at the beginning of the object it is restarted, since a bao proof can only be checked from the start.
+func (r *seekableSiaStream) finishVerify() error {
+	if r.verify == nil || r.verified {
+		return nil
+	}
+
+	if !r.fromStart {
+		_, err := r.Seek(0, io.SeekStart)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := io.Copy(io.Discard, r)
+	if err != nil {
+		return err
 	}
 
-	if offset == 0 && whence == io.SeekEnd {
-		return r.size, nil
+	if !r.verified {
+		return errObjectNotVerified
 	}
 
-	return 0, errors.New("seek not supported")
+	return nil
 }
 
 func (r *seekableSiaStream) Close() error {
 	return r.rc.Close()
 }
 
-func CronTaskUploadObject(input any, ctx core.Context) error {
+func CronTaskUploadObject(input any, ctx core.Context) (err error) {
 	args, ok := input.(*define.CronTaskUploadObjectArgs)
 	if !ok {
 		return errors.New("invalid arguments type")
 	}
 
+	setImportStatus(ctx, args.ImportID, types.ImportStatusUploading, nil)
+	defer func() {
+		if err != nil {
+			setImportStatus(ctx, args.ImportID, types.ImportStatusFailed, err)
+		}
+	}()
+
+	err = uploadSyncObject(ctx, args, nil)
+	if err != nil {
+		return err
+	}
+
+	setImportStatus(ctx, args.ImportID, types.ImportStatusCompleted, nil)
+
+	return nil
+}
+
+// CronTaskVerifyUploadObject verifies and uploads an object in one pass. The renter stream is run through the bao verifier on its way into storage, and the upload is rolled back if verification fails, so each candidate is only downloaded once.
+func CronTaskVerifyUploadObject(input any, ctx core.Context) (err error) {
+	args, ok := input.(*define.CronTaskVerifyObjectArgs)
+	if !ok {
+		return errors.New("invalid arguments type")
+	}
+
+	setImportStatus(ctx, args.ImportID, types.ImportStatusVerifying, nil)
+	defer func() {
+		if err != nil {
+			setImportStatus(ctx, args.ImportID, types.ImportStatusFailed, err)
+		}
+	}()
+
+	logger := ctx.Logger()
+	renter := ctx.Service(core.RENTER_SERVICE).(core.RenterService)
+	err = renter.CreateBucketIfNotExists(syncBucketName)
+	if err != nil {
+		return err
+	}
+
+	for _, object_ := range args.Object {
+		if !bytes.Equal(object_.Hash, args.Hash) {
+			logger.Error("hash mismatch", zap.Binary("expected", args.Hash), zap.Binary("actual", object_.Hash))
+			continue
+		}
+
+		fileName, err := encodeProtocolFileName(object_.Hash, object_.Protocol)
+		if err != nil {
+			logger.Error("failed to encode protocol file name", zap.Error(err))
+			return err
+		}
+
+		err = renter.ImportObjectMetadata(ctx, syncBucketName, fileName, object.Object{
+			Key:   object_.Key,
+			Slabs: object_.Slabs,
+		})
+
+		if err != nil {
+			logger.Error("failed to import object metadata", zap.Error(err))
+			continue
+		}
+
+		setImportStatus(ctx, args.ImportID, types.ImportStatusUploading, nil)
+
+		err = uploadSyncObject(ctx, &define.CronTaskUploadObjectArgs{
+			Hash:       args.Hash,
+			Protocol:   object_.Protocol,
+			Size:       object_.Size,
+			UploaderID: args.UploaderID,
+			ImportID:   args.ImportID,
+		}, &bao.Result{
+			Hash:   object_.Hash,
+			Proof:  object_.Proof,
+			Length: uint(object_.Size),
+		})
+		if err != nil {
+			logger.Error("failed to verify and upload object", zap.Error(err))
+			continue
+		}
+
+		setImportStatus(ctx, args.ImportID, types.ImportStatusCompleted, nil)
+
+		return nil
+	}
+
+	setImportStatus(ctx, args.ImportID, types.ImportStatusFailed, errObjectNotVerified)
+
+	return nil
+}
+
+// uploadSyncObject copies an object from the sync bucket into storage and records it for the uploader. When verify is set the data is checked against the proof while it streams, and what the upload stored is rolled back if it does not match.
+func uploadSyncObject(ctx core.Context, args *define.CronTaskUploadObjectArgs, verify *bao.Result) error {
 	logger := ctx.Logger()
 	renter := ctx.Service(core.RENTER_SERVICE).(core.RenterService)
 	storage := ctx.Service(core.STORAGE_SERVICE).(core.StorageService)
@@ -200,18 +419,43 @@ func CronTaskUploadObject(input any, ctx core.Context) error {
 	storeProtocol := syncProtocol.StorageProtocol()
 
 	wrapper := &seekableSiaStream{
-		rc:   objectRet.Content,
-		ctx:  ctx,
-		args: args,
-		size: objectRet.Size,
+		ctx:       ctx,
+		args:      args,
+		size:      objectRet.Size,
+		verify:    verify,
+		fromStart: true,
 	}
+	wrapper.rc = wrapper.wrap(objectRet.Content)
+
+	defer func() {
+		if err := wrapper.Close(); err != nil {
+			logger.Error("failed to close object stream", zap.Error(err))
+		}
+	}()
 
 	upload, err := storage.UploadObject(ctx, storeProtocol, wrapper, args.Size, nil, nil)
 
 	if err != nil {
+		// The verifier fails the read mid-stream, which can leave part of the object stored under the expected hash
+		if verify != nil {
+			rollbackUpload(ctx, storeProtocol, args.Hash)
+		}
 		return err
 	}
 
+	if verify != nil {
+		// Make sure the verifier has seen the whole object, not just what the upload read.
+		err = wrapper.finishVerify()
+		if err == nil && !bytes.Equal(upload.Hash, args.Hash) {
+			err = errUploadHashMismatch
+		}
+
+		if err != nil {
+			rollbackUpload(ctx, storeProtocol, upload.Hash)
+			return err
+		}
+	}
+
 	upload.UserID = uint(args.UploaderID)
 
 	err = meta.SaveUpload(ctx, *upload, true)
@@ -232,21 +476,198 @@ func CronTaskUploadObject(input any, ctx core.Context) error {
 	return nil
 }
 
-func CronTaskScanObjects(_ any, ctx core.Context) error {
+// rollbackUpload deletes an object an unverified upload stored under hash. Objects with an upload record belong to whoever uploaded that content before, and are left alone.
+func rollbackUpload(ctx core.Context, storeProtocol core.StorageProtocol, hash []byte) {
 	logger := ctx.Logger()
+	renter := ctx.Service(core.RENTER_SERVICE).(core.RenterService)
 	meta := ctx.Service(core.METADATA_SERVICE).(core.MetadataService)
+
+	existing, err := meta.GetUpload(ctx, hash)
+	if err == nil && !existing.IsEmpty() {
+		logger.Debug("not rolling back upload of existing object", zap.Binary("hash", hash))
+		return
+	}
+
+	err = renter.DeleteObjectMetadata(ctx, storeProtocol.Name(), storeProtocol.EncodeFileName(hash))
+	if err != nil {
+		logger.Error("failed to roll back unverified upload", zap.Error(err))
+	}
+}
+
+// CronTaskScanObjects publishes uploads that are new since the last scan. Uploads at or below the persisted cursor are only revisited on a periodic full rescan, and Update skips any whose slab fingerprint has not changed since it was last published.
+//
+// Uploads are read from the database a page at a time and each page is processed by a bounded pool of workers. If the portal shuts down mid-scan, no new uploads are dispatched, in-flight ones finish, and the cursor only advances past uploads that were actually processed.
+func CronTaskScanObjects(_ any, ctx core.Context) error {
+	logger := ctx.Logger()
 	_sync := ctx.Service(types.SYNC_SERVICE).(types.SyncService)
-	uploads, err := meta.GetAllUploads(ctx)
+	scanConfig := getScanObjectsConfig(ctx)
+
+	err := db.CheckUploadsTable(ctx, ctx.DB())
 	if err != nil {
 		return err
 	}
 
-	for _, upload := range uploads {
-		err := _sync.Update(upload)
+	cursor, err := db.GetScanCursor(ctx, ctx.DB(), define.CronTaskScanObjectsName)
+	if err != nil {
+		return err
+	}
+
+	fullScan := time.Since(cursor.FullScanAt) >= define.CronTaskScanObjectsFullRescanInterval
+
+	after := cursor.UploadID
+	if fullScan {
+		after = 0
+	}
+
+	var processed, failed atomic.Int64
+
+	stopProgress := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(scanConfig.ProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopProgress:
+				return
+			case <-ticker.C:
+				logger.Info("scanning uploads", zap.Int64("processed", processed.Load()), zap.Int64("failed", failed.Load()))
+			}
+		}
+	}()
+
+	complete := false
+	for ctx.Err() == nil {
+		var page []core.UploadMetadata
+		page, err = db.GetUploadsAfter(ctx, ctx.DB(), after, define.CronTaskScanObjectsPageSize)
 		if err != nil {
-			logger.Error("failed to update upload", zap.Error(err))
+			break
+		}
+
+		if len(page) == 0 {
+			complete = true
+			break
+		}
+
+		done := scanUploads(ctx, _sync, page, scanConfig.Workers, &processed, &failed)
+
+		// Only advance past the uploads that were processed without a gap, so anything skipped by a shutdown is picked up next time.
+		for _, upload := range page[:done] {
+			if upload.ID > cursor.UploadID {
+				cursor.UploadID = upload.ID
+			}
+		}
+
+		if done < len(page) {
+			break
 		}
+
+		after = page[len(page)-1].ID
 	}
 
-	return nil
+	close(stopProgress)
+
+	if fullScan && complete {
+		cursor.FullScanAt = time.Now()
+	}
+
+	// Persist progress even when the scan was cancelled or failed.
+	saveErr := db.SaveScanCursor(context.WithoutCancel(ctx), ctx.DB(), cursor)
+	if err != nil {
+		return err
+	}
+	if saveErr != nil {
+		return saveErr
+	}
+
+	logger.Info("scanned uploads", zap.Int64("processed", processed.Load()), zap.Int64("failed", failed.Load()), zap.Bool("full", fullScan), zap.Uint("cursor", cursor.UploadID))
+
+	return ctx.Err()
+}
+
+// scanUploads publishes a page of uploads on a pool of workers and returns how many uploads from the start of the page were processed without a gap.
+func scanUploads(ctx core.Context, _sync types.SyncService, uploads []core.UploadMetadata, workers int, processed *atomic.Int64, failed *atomic.Int64) int {
+	logger := ctx.Logger()
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		done = make([]bool, len(uploads))
+		jobs = make(chan int)
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				err := _sync.Update(ctx, uploads[i])
+				switch {
+				case err == nil:
+				case ctx.Err() != nil:
+					continue
+				case errors.Is(err, types.ErrObjectNoShards):
+					// Empty and inline uploads have nothing to publish
+					logger.Debug("skipping upload without shards", zap.Uint("upload", uploads[i].ID))
+				default:
+					failed.Add(1)
+					logger.Error("failed to update upload", zap.Error(err), zap.Uint("upload", uploads[i].ID))
+				}
+
+				mu.Lock()
+				done[i] = true
+				mu.Unlock()
+				processed.Add(1)
+			}
+		}()
+	}
+
+dispatch:
+	for i := range uploads {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case jobs <- i:
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	for i := range done {
+		if !done[i] {
+			return i
+		}
+	}
+
+	return len(done)
+}
+
+func getScanObjectsConfig(ctx core.Context) define.ScanObjectsConfig {
+	scanConfig := define.ScanObjectsConfig{}
+
+	if configurer, ok := ctx.Config().GetService(types.SYNC_SERVICE).(define.ScanObjectsConfigurer); ok {
+		scanConfig = configurer.ScanObjectsConfig()
+	}
+
+	if scanConfig.Workers <= 0 {
+		scanConfig.Workers = 1
+	}
+
+	if scanConfig.ProgressInterval <= 0 {
+		scanConfig.ProgressInterval = time.Minute
+	}
+
+	return scanConfig
+}
+
+func CronTaskRetryPublish(_ any, ctx core.Context) error {
+	_sync := ctx.Service(types.SYNC_SERVICE).(types.SyncService)
+
+	return _sync.RetryPublishes(ctx)
+}
+
+func CronTaskRemoveDeleted(_ any, ctx core.Context) error {
+	_sync := ctx.Service(types.SYNC_SERVICE).(types.SyncService)
+
+	return _sync.RemoveDeleted(ctx)
 }
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ScanCursor is the high-water mark of a scan over uploads. Uploads with an ID at or below UploadID have already been scanned.
+type ScanCursor struct {
+	Name       string `gorm:"primaryKey;size:64"`
+	UploadID   uint
+	FullScanAt time.Time
+	UpdatedAt  time.Time
+}
+
+func (ScanCursor) TableName() string {
+	return "sync_scan_cursors"
+}
+
+// GetScanCursor returns the cursor called name, or a new zero cursor if it does not exist yet.
+func GetScanCursor(ctx context.Context, tx *gorm.DB, name string) (*ScanCursor, error) {
+	var cursor ScanCursor
+
+	err := tx.WithContext(ctx).Where(&ScanCursor{Name: name}).First(&cursor).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &ScanCursor{Name: name}, nil
+		}
+		return nil, err
+	}
+
+	return &cursor, nil
+}
+
+func SaveScanCursor(ctx context.Context, tx *gorm.DB, cursor *ScanCursor) error {
+	return tx.WithContext(ctx).Save(cursor).Error
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"go.lumeweb.com/portal-plugin-sync/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Denied is a hash that may never be imported or published.
+type Denied struct {
+	gorm.Model
+	Hash   []byte `gorm:"type:varbinary(64);uniqueIndex"`
+	Reason string
+}
+
+func (Denied) TableName() string {
+	return "sync_denylist"
+}
+
+func (d Denied) ToInfo() types.DenylistEntry {
+	return types.DenylistEntry{
+		Hash:      d.Hash,
+		Reason:    d.Reason,
+		CreatedAt: d.CreatedAt,
+	}
+}
+
+func IsDenied(ctx context.Context, tx *gorm.DB, hash []byte) (bool, error) {
+	var count int64
+
+	err := tx.WithContext(ctx).Model(&Denied{}).Where(&Denied{Hash: hash}).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// SaveDenied adds hashes to the denylist, updating the reason of any that are already listed.
+func SaveDenied(ctx context.Context, tx *gorm.DB, records []Denied) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	return tx.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"reason", "updated_at"}),
+	}).CreateInBatches(records, 500).Error
+}
+
+func DeleteDenied(ctx context.Context, tx *gorm.DB, hash []byte) error {
+	result := tx.WithContext(ctx).Unscoped().Where(&Denied{Hash: hash}).Delete(&Denied{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return types.ErrDenylistEntryNotFound
+	}
+
+	return nil
+}
+
+func ListDenied(ctx context.Context, tx *gorm.DB) ([]Denied, error) {
+	var records []Denied
+
+	err := tx.WithContext(ctx).Order("id").Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"go.lumeweb.com/portal-plugin-sync/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// Import tracks a single object import through the verify and upload pipeline.
+type Import struct {
+	gorm.Model
+	UserID   uint   `gorm:"index"`
+	Hash     []byte `gorm:"type:varbinary(64);index"`
+	Object   string
+	Protocol string
+	Size     uint64
+	Status   types.ImportStatus `gorm:"index"`
+	Error    string
+}
+
+func (Import) TableName() string {
+	return "sync_imports"
+}
+
+func (i Import) ToInfo() types.ImportInfo {
+	return types.ImportInfo{
+		ID:        i.ID,
+		UserID:    i.UserID,
+		Hash:      i.Hash,
+		Object:    i.Object,
+		Protocol:  i.Protocol,
+		Size:      i.Size,
+		Status:    i.Status,
+		Error:     i.Error,
+		CreatedAt: i.CreatedAt,
+		UpdatedAt: i.UpdatedAt,
+	}
+}
+
+func CreateImport(ctx context.Context, tx *gorm.DB, record *Import) error {
+	if record.Status == "" {
+		record.Status = types.ImportStatusQueued
+	}
+
+	return tx.WithContext(ctx).Create(record).Error
+}
+
+func GetImport(ctx context.Context, tx *gorm.DB, id uint) (*Import, error) {
+	var record Import
+
+	err := tx.WithContext(ctx).First(&record, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrImportNotFound
+		}
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func ListImports(ctx context.Context, tx *gorm.DB, userID uint) ([]Import, error) {
+	var records []Import
+
+	err := tx.WithContext(ctx).Where(&Import{UserID: userID}).Order("id desc").Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// ImportQuotaLock is a per-user row that imports lock while they check quota and record themselves, so nodes sharing the database admit a user's imports one at a time.
+type ImportQuotaLock struct {
+	UserID uint `gorm:"primaryKey;autoIncrement:false"`
+}
+
+func (ImportQuotaLock) TableName() string {
+	return "sync_import_quota_locks"
+}
+
+// LockImportQuota locks userID's quota row until tx ends, creating the row on first use. tx must be a transaction.
+func LockImportQuota(ctx context.Context, tx *gorm.DB, userID uint) error {
+	err := tx.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&ImportQuotaLock{UserID: userID}).Error
+	if err != nil {
+		return err
+	}
+
+	var lock ImportQuotaLock
+
+	return tx.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where(&ImportQuotaLock{UserID: userID}).First(&lock).Error
+}
+
+// ImportUsage is what a user's imports count against their quota.
+type ImportUsage struct {
+	// Active is the number of imports that are still being processed.
+	Active int64
+	// Recent is the number of imports created since the requested time.
+	Recent int64
+	// Bytes is the total size of every import that has not failed.
+	Bytes uint64
+}
+
+func GetImportUsage(ctx context.Context, tx *gorm.DB, userID uint, since time.Time) (*ImportUsage, error) {
+	var usage ImportUsage
+
+	query := func() *gorm.DB {
+		return tx.WithContext(ctx).Model(&Import{}).Where(&Import{UserID: userID})
+	}
+
+	err := query().Where("status NOT IN ?", []types.ImportStatus{types.ImportStatusCompleted, types.ImportStatusFailed}).Count(&usage.Active).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = query().Where("created_at >= ?", since).Count(&usage.Recent).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = query().Where("status <> ?", types.ImportStatusFailed).Select("COALESCE(SUM(size), 0)").Scan(&usage.Bytes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
+// UpdateImportStatus moves an import to status, recording cause as its error when set. Jobs queued without an import record use ID 0 and are ignored.
+func UpdateImportStatus(ctx context.Context, tx *gorm.DB, id uint, status types.ImportStatus, cause error) error {
+	if id == 0 {
+		return nil
+	}
+
+	errMsg := ""
+	if cause != nil {
+		errMsg = cause.Error()
+	}
+
+	return tx.WithContext(ctx).Model(&Import{}).Where("id = ?", id).Updates(map[string]any{
+		"status": status,
+		"error":  errMsg,
+	}).Error
+}
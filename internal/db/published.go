@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Published records the last version of an upload that was written to the sync log, identified by its slab fingerprint.
+type Published struct {
+	gorm.Model
+	UploadID    uint   `gorm:"index"`
+	Hash        []byte `gorm:"type:varbinary(64);uniqueIndex"`
+	Protocol    string
+	Fingerprint []byte `gorm:"type:varbinary(32)"`
+}
+
+func (Published) TableName() string {
+	return "sync_published"
+}
+
+// GetPublished returns the publish record for hash, or nil if it has never been published.
+func GetPublished(ctx context.Context, tx *gorm.DB, hash []byte) (*Published, error) {
+	var record Published
+
+	err := tx.WithContext(ctx).Where(&Published{Hash: hash}).First(&record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func SavePublished(ctx context.Context, tx *gorm.DB, uploadID uint, hash []byte, protocol string, fingerprint []byte) error {
+	return tx.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"upload_id", "protocol", "fingerprint", "updated_at"}),
+	}).Create(&Published{
+		UploadID:    uploadID,
+		Hash:        hash,
+		Protocol:    protocol,
+		Fingerprint: fingerprint,
+	}).Error
+}
+
+func DeletePublished(ctx context.Context, tx *gorm.DB, hash []byte) error {
+	return tx.WithContext(ctx).Unscoped().Where(&Published{Hash: hash}).Delete(&Published{}).Error
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"go.lumeweb.com/portal-plugin-sync/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// PublishRetry is an upload whose publish to the sync log failed and is waiting to be retried. Once it runs out of attempts it is marked dead and only an operator can requeue it.
+type PublishRetry struct {
+	gorm.Model
+	UploadID      uint
+	Hash          []byte `gorm:"type:varbinary(64);uniqueIndex"`
+	Protocol      string
+	Attempts      int
+	NextAttemptAt time.Time `gorm:"index"`
+	LastError     string
+	Dead          bool `gorm:"index"`
+}
+
+func (PublishRetry) TableName() string {
+	return "sync_publish_retries"
+}
+
+func (r PublishRetry) ToInfo() types.PublishRetryInfo {
+	return types.PublishRetryInfo{
+		ID:        r.ID,
+		UploadID:  r.UploadID,
+		Hash:      r.Hash,
+		Protocol:  r.Protocol,
+		Attempts:  r.Attempts,
+		LastError: r.LastError,
+		UpdatedAt: r.UpdatedAt,
+	}
+}
+
+// GetPublishRetry returns the retry queued for hash, or nil if there is none.
+func GetPublishRetry(ctx context.Context, tx *gorm.DB, hash []byte) (*PublishRetry, error) {
+	var record PublishRetry
+
+	err := tx.WithContext(ctx).Where(&PublishRetry{Hash: hash}).First(&record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// QueuePublishRetry schedules a retry for hash. If another failure queued one first, the failure is counted as an attempt against it, but its next attempt is left as scheduled since the caller does not know how far that retry has backed off.
+func QueuePublishRetry(ctx context.Context, tx *gorm.DB, uploadID uint, hash []byte, protocol string, cause error, next time.Time) error {
+	return tx.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "hash"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"attempts":   gorm.Expr("attempts + 1"),
+			"last_error": cause.Error(),
+			"updated_at": time.Now(),
+		}),
+	}).Create(&PublishRetry{
+		UploadID:      uploadID,
+		Hash:          hash,
+		Protocol:      protocol,
+		NextAttemptAt: next,
+		LastError:     cause.Error(),
+	}).Error
+}
+
+func DuePublishRetries(ctx context.Context, tx *gorm.DB, now time.Time, limit int) ([]PublishRetry, error) {
+	var records []PublishRetry
+
+	err := tx.WithContext(ctx).Where("dead = ? AND next_attempt_at <= ?", false, now).Order("next_attempt_at").Limit(limit).Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func SavePublishRetry(ctx context.Context, tx *gorm.DB, record *PublishRetry) error {
+	return tx.WithContext(ctx).Save(record).Error
+}
+
+func DeletePublishRetry(ctx context.Context, tx *gorm.DB, id uint) error {
+	return tx.WithContext(ctx).Unscoped().Delete(&PublishRetry{}, id).Error
+}
+
+func DeletePublishRetryByHash(ctx context.Context, tx *gorm.DB, hash []byte) error {
+	return tx.WithContext(ctx).Unscoped().Where(&PublishRetry{Hash: hash}).Delete(&PublishRetry{}).Error
+}
+
+func ListDeadPublishRetries(ctx context.Context, tx *gorm.DB) ([]PublishRetry, error) {
+	var records []PublishRetry
+
+	err := tx.WithContext(ctx).Where("dead = ?", true).Order("updated_at desc").Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// RequeuePublishRetry revives a dead retry with a fresh set of attempts, due immediately.
+func RequeuePublishRetry(ctx context.Context, tx *gorm.DB, id uint, now time.Time) error {
+	var record PublishRetry
+
+	err := tx.WithContext(ctx).Where("id = ? AND dead = ?", id, true).First(&record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return types.ErrDeadLetterNotFound
+		}
+		return err
+	}
+
+	record.Dead = false
+	record.Attempts = 0
+	record.NextAttemptAt = now
+
+	return tx.WithContext(ctx).Save(&record).Error
+}
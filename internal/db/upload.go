@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.lumeweb.com/portal/core"
+	"gorm.io/gorm"
+)
+
+var ErrUploadsSchema = errors.New("portal uploads table does not match the scan")
+
+// upload reads the columns of the portal's uploads table that a scan needs, so uploads can be paged without loading them all through the metadata service.
+type upload struct {
+	ID         uint
+	UserID     uint
+	Hash       []byte
+	MimeType   string
+	Protocol   string
+	UploaderIP string
+	Size       uint64
+	DeletedAt  gorm.DeletedAt
+}
+
+func (upload) TableName() string {
+	return "uploads"
+}
+
+// CheckUploadsTable fails if the portal's uploads table is missing or lacks any column upload reads. The scan bypasses the metadata service, so a portal schema change has to be caught here rather than show up as empty pages.
+func CheckUploadsTable(ctx context.Context, tx *gorm.DB) error {
+	tx = tx.WithContext(ctx)
+
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(&upload{}); err != nil {
+		return err
+	}
+
+	migrator := tx.Migrator()
+	if !migrator.HasTable(&upload{}) {
+		return fmt.Errorf("%w: table %s is missing", ErrUploadsSchema, stmt.Schema.Table)
+	}
+
+	for _, column := range stmt.Schema.DBNames {
+		if !migrator.HasColumn(&upload{}, column) {
+			return fmt.Errorf("%w: column %s.%s is missing", ErrUploadsSchema, stmt.Schema.Table, column)
+		}
+	}
+
+	return nil
+}
+
+// GetUploadsAfter returns up to limit uploads with an ID above afterID, in ID order.
+func GetUploadsAfter(ctx context.Context, tx *gorm.DB, afterID uint, limit int) ([]core.UploadMetadata, error) {
+	var records []upload
+
+	err := tx.WithContext(ctx).Where("id > ?", afterID).Order("id").Limit(limit).Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	uploads := make([]core.UploadMetadata, 0, len(records))
+	for _, record := range records {
+		uploads = append(uploads, core.UploadMetadata{
+			ID:         record.ID,
+			UserID:     record.UserID,
+			Hash:       record.Hash,
+			MimeType:   record.MimeType,
+			Protocol:   record.Protocol,
+			UploaderIP: record.UploaderIP,
+			Size:       record.Size,
+		})
+	}
+
+	return uploads, nil
+}
+
+// GetDeletedPublished returns up to limit publish records, with an ID above afterID, whose hash no longer has a live upload.
+func GetDeletedPublished(ctx context.Context, tx *gorm.DB, afterID uint, limit int) ([]Published, error) {
+	var records []Published
+
+	tx = tx.WithContext(ctx)
+	live := tx.Model(&upload{}).Select("hash")
+
+	err := tx.Where("id > ? AND hash NOT IN (?)", afterID, live).Order("id").Limit(limit).Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
@@ -0,0 +1,57 @@
+package denylist
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+var errInvalidHash = errors.New("invalid hash")
+
+// Entry is a single blocked hash and an optional reason.
+type Entry struct {
+	Hash   []byte
+	Reason string
+}
+
+// Parse reads a denylist in the shared line-based format: one hex encoded hash per line, optionally followed by whitespace and a reason. Blank lines and lines starting with # are ignored.
+func Parse(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		hashHex, reason := text, ""
+		if i := strings.IndexFunc(text, unicode.IsSpace); i != -1 {
+			hashHex, reason = text[:i], text[i:]
+		}
+
+		hash, err := hex.DecodeString(hashHex)
+		if err != nil || len(hash) == 0 || len(hash) > 64 {
+			return nil, fmt.Errorf("line %d: %w", line, errInvalidHash)
+		}
+
+		entries = append(entries, Entry{
+			Hash:   hash,
+			Reason: strings.TrimSpace(reason),
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,64 @@
+package denylist
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Entry
+	}{
+		{"empty", "", nil},
+		{"comments and blank lines", "# header\n\n   \n  # indented comment\n", nil},
+		{"hash only", "abcd\n", []Entry{{Hash: []byte{0xab, 0xcd}}}},
+		{"hash and reason", "abcd  dmca takedown\n", []Entry{{Hash: []byte{0xab, 0xcd}, Reason: "dmca takedown"}}},
+		{"tab separated", "abcd\tspam", []Entry{{Hash: []byte{0xab, 0xcd}, Reason: "spam"}}},
+		{"surrounding whitespace", "  01  reason  \r\n", []Entry{{Hash: []byte{0x01}, Reason: "reason"}}},
+		{"several lines", "01\n# skip\n02 two\n", []Entry{{Hash: []byte{0x01}}, {Hash: []byte{0x02}, Reason: "two"}}},
+		{"longest hash", strings.Repeat("ff", 64), []Entry{{Hash: []byte(strings.Repeat("\xff", 64))}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Parse = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		line  string
+	}{
+		{"not hex", "zz\n", "line 1"},
+		{"odd length", "abc\n", "line 1"},
+		{"too long", strings.Repeat("ff", 65), "line 1"},
+		{"reports the line", "# ok\n01\nnope\n", "line 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.input))
+			if !errors.Is(err, errInvalidHash) {
+				t.Fatalf("expected errInvalidHash, got %v", err)
+			}
+
+			if !strings.HasPrefix(err.Error(), tt.line+":") {
+				t.Fatalf("expected error for %s, got %v", tt.line, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package metadata
+
+// segmentSize is the smallest unit a shard can be downloaded in.
+const segmentSize = 64
+
+// DownloadSize estimates how many bytes the renter has to fetch from hosts to download the object. Slab data is striped across MinShards shards one segment at a time, so every slice is rounded out to whole stripes.
+func (fm *FileMeta) DownloadSize() uint64 {
+	var size uint64
+
+	for _, slab := range fm.Slabs {
+		minShards := uint64(slab.MinShards)
+		if minShards == 0 {
+			minShards = 1
+		}
+
+		stripe := minShards * segmentSize
+		start := uint64(slab.Offset) / stripe
+		end := (uint64(slab.Offset) + uint64(slab.Length) + stripe - 1) / stripe
+
+		size += (end - start) * stripe
+	}
+
+	return size
+}
@@ -1,7 +1,11 @@
 package metadata
 
 import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
 	"go.lumeweb.com/portal-plugin-sync-grpc/gen/proto"
+	sync "go.lumeweb.com/portal-plugin-sync/internal/p2p"
 	"go.sia.tech/core/types"
 	"go.sia.tech/renterd/object"
 )
@@ -15,6 +19,10 @@ type FileMeta struct {
 	Size      uint64 `json:"size"`
 	Slabs     []object.SlabSlice
 	Aliases   []string `json:"aliases"`
+	// Writer is the key that signed the log entry this was read from.
+	Writer ed25519.PublicKey `json:"-"`
+	// LogProof proves the entry is a block of Writer's core.
+	LogProof *sync.BlockProof `json:"-"`
 }
 
 func (fm *FileMeta) ToProtobuf() *proto.FileMeta {
@@ -134,6 +142,11 @@ func FileMetaFromProtobuf(fm *proto.FileMeta) (*FileMeta, error) {
 		})
 	}
 
+	writer, logProof, err := decodeLogFields(fm.ProtoReflect().GetUnknown())
+	if err != nil {
+		return nil, err
+	}
+
 	return &FileMeta{
 		Hash:      fm.Hash,
 		Multihash: fm.Multihash,
@@ -143,5 +156,37 @@ func FileMetaFromProtobuf(fm *proto.FileMeta) (*FileMeta, error) {
 		Size:      fm.Size,
 		Slabs:     slabSlices,
 		Aliases:   fm.Aliases,
+		Writer:    writer,
+		LogProof:  logProof,
 	}, nil
 }
+
+// Fingerprint hashes the parts of an object's layout that change when it is re-uploaded or migrated: its key and each slab's key, range and shard placement. Health is left out since it changes constantly.
+func Fingerprint(key object.EncryptionKey, slabs []object.SlabSlice) []byte {
+	h := sha256.New()
+
+	keyBytes, _ := key.MarshalBinary()
+	h.Write(keyBytes)
+
+	var buf [8]byte
+	for _, slab := range slabs {
+		slabKey, _ := slab.Key.MarshalBinary()
+		h.Write(slabKey)
+
+		binary.BigEndian.PutUint32(buf[:4], slab.Offset)
+		binary.BigEndian.PutUint32(buf[4:], slab.Length)
+		h.Write(buf[:])
+		h.Write([]byte{slab.MinShards})
+
+		for _, shard := range slab.Shards {
+			h.Write(shard.Root[:])
+			h.Write(shard.LatestHost[:])
+		}
+	}
+
+	return h.Sum(nil)
+}
+
+func (fm *FileMeta) Fingerprint() []byte {
+	return Fingerprint(fm.Key, fm.Slabs)
+}
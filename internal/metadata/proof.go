@@ -0,0 +1,183 @@
+package metadata
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	sync "go.lumeweb.com/portal-plugin-sync/internal/p2p"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// A backend can attach the writer of each entry and its block proof to FileMeta under these field numbers. They are not
+// part of the shared proto, and the node sidecar does not send them yet, so they can only arrive as unknown fields.
+const (
+	fieldWriter   protowire.Number = 100
+	fieldLogProof protowire.Number = 101
+)
+
+// BlockProof fields.
+const (
+	fieldProofIndex      protowire.Number = 1
+	fieldProofValue      protowire.Number = 2
+	fieldProofNodes      protowire.Number = 3
+	fieldProofRoots      protowire.Number = 4
+	fieldProofLength     protowire.Number = 5
+	fieldProofFork       protowire.Number = 6
+	fieldProofSignatures protowire.Number = 7
+)
+
+// TreeNode and Signature fields.
+const (
+	fieldNodeIndex protowire.Number = 1
+	fieldNodeSize  protowire.Number = 2
+	fieldNodeHash  protowire.Number = 3
+
+	fieldSignatureSigner    protowire.Number = 1
+	fieldSignatureSignature protowire.Number = 2
+)
+
+var errInvalidLogFields = errors.New("invalid log proof fields")
+
+// decodeLogFields reads the writer and log proof out of a FileMeta's unknown fields. Either is nil if it is not present.
+func decodeLogFields(unknown []byte) (ed25519.PublicKey, *sync.BlockProof, error) {
+	var writer ed25519.PublicKey
+	var proof *sync.BlockProof
+
+	err := walkFields(unknown, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+
+		switch num {
+		case fieldWriter:
+			if len(value) != ed25519.PublicKeySize {
+				return fmt.Errorf("%w: writer must be %d bytes, got %d", errInvalidLogFields, ed25519.PublicKeySize, len(value))
+			}
+			writer = ed25519.PublicKey(value)
+		case fieldLogProof:
+			var err error
+			proof, err = decodeBlockProof(value)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return writer, proof, nil
+}
+
+func decodeBlockProof(data []byte) (*sync.BlockProof, error) {
+	proof := &sync.BlockProof{}
+
+	err := walkFields(data, func(num protowire.Number, _ protowire.Type, value []byte, n uint64) error {
+		switch num {
+		case fieldProofIndex:
+			proof.Index = n
+		case fieldProofValue:
+			proof.Value = value
+		case fieldProofNodes, fieldProofRoots:
+			node, err := decodeTreeNode(value)
+			if err != nil {
+				return err
+			}
+
+			if num == fieldProofNodes {
+				proof.Nodes = append(proof.Nodes, node)
+			} else {
+				proof.Roots = append(proof.Roots, node)
+			}
+		case fieldProofLength:
+			proof.Length = n
+		case fieldProofFork:
+			proof.Fork = n
+		case fieldProofSignatures:
+			signature, err := decodeSignature(value)
+			if err != nil {
+				return err
+			}
+			proof.Signatures = append(proof.Signatures, signature)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return proof, nil
+}
+
+func decodeTreeNode(data []byte) (sync.TreeNode, error) {
+	var node sync.TreeNode
+
+	err := walkFields(data, func(num protowire.Number, _ protowire.Type, value []byte, n uint64) error {
+		switch num {
+		case fieldNodeIndex:
+			node.Index = n
+		case fieldNodeSize:
+			node.Size = n
+		case fieldNodeHash:
+			node.Hash = value
+		}
+
+		return nil
+	})
+
+	return node, err
+}
+
+func decodeSignature(data []byte) (sync.Signature, error) {
+	var signature sync.Signature
+
+	err := walkFields(data, func(num protowire.Number, _ protowire.Type, value []byte, n uint64) error {
+		switch num {
+		case fieldSignatureSigner:
+			signature.Signer = int(n)
+		case fieldSignatureSignature:
+			signature.Signature = value
+		}
+
+		return nil
+	})
+
+	return signature, err
+}
+
+// walkFields calls fn for every field in data. Length delimited fields are passed as value and varints as n; other
+// wire types are skipped.
+func walkFields(data []byte, fn func(num protowire.Number, typ protowire.Type, value []byte, n uint64) error) error {
+	for len(data) > 0 {
+		num, typ, length := protowire.ConsumeTag(data)
+		if length < 0 {
+			return fmt.Errorf("%w: %v", errInvalidLogFields, protowire.ParseError(length))
+		}
+		data = data[length:]
+
+		var value []byte
+		var n uint64
+
+		switch typ {
+		case protowire.BytesType:
+			value, length = protowire.ConsumeBytes(data)
+		case protowire.VarintType:
+			n, length = protowire.ConsumeVarint(data)
+		default:
+			length = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if length < 0 {
+			return fmt.Errorf("%w: %v", errInvalidLogFields, protowire.ParseError(length))
+		}
+		data = data[length:]
+
+		if err := fn(num, typ, value, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
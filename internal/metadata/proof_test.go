@@ -0,0 +1,104 @@
+package metadata
+
+import (
+	"bytes"
+	"errors"
+	"google.golang.org/protobuf/encoding/protowire"
+	"testing"
+)
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func treeNode(index uint64, size uint64, hash byte) []byte {
+	var b []byte
+	b = appendVarint(b, fieldNodeIndex, index)
+	b = appendVarint(b, fieldNodeSize, size)
+	return appendMessage(b, fieldNodeHash, bytes.Repeat([]byte{hash}, 32))
+}
+
+func TestDecodeLogFields(t *testing.T) {
+	writer := bytes.Repeat([]byte{1}, 32)
+
+	var signature []byte
+	signature = appendVarint(signature, fieldSignatureSigner, 2)
+	signature = appendMessage(signature, fieldSignatureSignature, []byte("sig"))
+
+	var proof []byte
+	proof = appendVarint(proof, fieldProofIndex, 2)
+	proof = appendMessage(proof, fieldProofValue, []byte("block"))
+	proof = appendMessage(proof, fieldProofNodes, treeNode(6, 5, 0xaa))
+	proof = appendMessage(proof, fieldProofRoots, treeNode(3, 20, 0xbb))
+	proof = appendMessage(proof, fieldProofRoots, treeNode(8, 5, 0xcc))
+	proof = appendVarint(proof, fieldProofLength, 5)
+	proof = appendVarint(proof, fieldProofFork, 1)
+	proof = appendMessage(proof, fieldProofSignatures, signature)
+
+	var unknown []byte
+	// Fields this version does not know about are skipped
+	unknown = appendVarint(unknown, 99, 7)
+	unknown = appendMessage(unknown, fieldWriter, writer)
+	unknown = appendMessage(unknown, fieldLogProof, proof)
+
+	gotWriter, got, err := decodeLogFields(unknown)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(gotWriter, writer) {
+		t.Fatalf("writer = %x, want %x", gotWriter, writer)
+	}
+
+	if got.Index != 2 || string(got.Value) != "block" || got.Length != 5 || got.Fork != 1 {
+		t.Fatalf("unexpected proof %+v", got)
+	}
+
+	if len(got.Nodes) != 1 || got.Nodes[0].Index != 6 || got.Nodes[0].Size != 5 || got.Nodes[0].Hash[0] != 0xaa {
+		t.Fatalf("unexpected nodes %+v", got.Nodes)
+	}
+
+	if len(got.Roots) != 2 || got.Roots[0].Index != 3 || got.Roots[1].Index != 8 || got.Roots[1].Hash[0] != 0xcc {
+		t.Fatalf("unexpected roots %+v", got.Roots)
+	}
+
+	if len(got.Signatures) != 1 || got.Signatures[0].Signer != 2 || string(got.Signatures[0].Signature) != "sig" {
+		t.Fatalf("unexpected signatures %+v", got.Signatures)
+	}
+}
+
+func TestDecodeLogFieldsMissing(t *testing.T) {
+	writer, proof, err := decodeLogFields(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if writer != nil || proof != nil {
+		t.Fatalf("expected no writer or proof, got %x %+v", writer, proof)
+	}
+}
+
+func TestDecodeLogFieldsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		unknown []byte
+	}{
+		{"short writer", appendMessage(nil, fieldWriter, []byte{1})},
+		{"truncated", appendMessage(nil, fieldLogProof, []byte("proof"))[:4]},
+		{"truncated proof", appendMessage(nil, fieldLogProof, []byte{byte(fieldProofValue<<3 | 2), 10})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeLogFields(tt.unknown); !errors.Is(err, errInvalidLogFields) {
+				t.Fatalf("expected errInvalidLogFields, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package metadata
+
+import (
+	"go.sia.tech/core/types"
+	"sort"
+)
+
+// Score ranks how likely a FileMeta is to download successfully. Fields are compared in order, so health dominates, then redundancy, then known hosts.
+type Score struct {
+	// Health is the health of the weakest slab.
+	Health float64
+	// Redundancy is the lowest ratio of available shards to MinShards across all slabs.
+	Redundancy float64
+	// KnownHosts is the fraction of shards last stored on a known-good host.
+	KnownHosts float64
+}
+
+func (s Score) Better(other Score) bool {
+	if s.Health != other.Health {
+		return s.Health > other.Health
+	}
+
+	if s.Redundancy != other.Redundancy {
+		return s.Redundancy > other.Redundancy
+	}
+
+	return s.KnownHosts > other.KnownHosts
+}
+
+func (fm *FileMeta) Score(knownHosts map[types.PublicKey]struct{}) Score {
+	var score Score
+
+	shards := 0
+	known := 0
+
+	for i, slab := range fm.Slabs {
+		if i == 0 || slab.Health < score.Health {
+			score.Health = slab.Health
+		}
+
+		minShards := float64(slab.MinShards)
+		if minShards == 0 {
+			minShards = 1
+		}
+
+		redundancy := float64(len(slab.Shards)) / minShards
+		if i == 0 || redundancy < score.Redundancy {
+			score.Redundancy = redundancy
+		}
+
+		for _, shard := range slab.Shards {
+			shards++
+			if _, ok := knownHosts[shard.LatestHost]; ok {
+				known++
+			}
+		}
+	}
+
+	if shards > 0 {
+		score.KnownHosts = float64(known) / float64(shards)
+	}
+
+	return score
+}
+
+// RankFileMeta sorts meta from best to worst candidate. Equal candidates keep their order.
+func RankFileMeta(meta []FileMeta, knownHosts map[types.PublicKey]struct{}) {
+	scores := make([]Score, len(meta))
+	for i := range meta {
+		scores[i] = meta[i].Score(knownHosts)
+	}
+
+	sort.Stable(byScore{meta: meta, scores: scores})
+}
+
+type byScore struct {
+	meta   []FileMeta
+	scores []Score
+}
+
+func (b byScore) Len() int {
+	return len(b.meta)
+}
+
+func (b byScore) Less(i, j int) bool {
+	return b.scores[i].Better(b.scores[j])
+}
+
+func (b byScore) Swap(i, j int) {
+	b.meta[i], b.meta[j] = b.meta[j], b.meta[i]
+	b.scores[i], b.scores[j] = b.scores[j], b.scores[i]
+}
@@ -0,0 +1,93 @@
+package metadata
+
+import (
+	"go.sia.tech/core/types"
+	"go.sia.tech/renterd/object"
+	"testing"
+)
+
+var (
+	knownHost   = types.PublicKey{1}
+	unknownHost = types.PublicKey{2}
+)
+
+func slab(health float64, minShards uint8, hosts ...types.PublicKey) object.SlabSlice {
+	shards := make([]object.Sector, 0, len(hosts))
+	for _, host := range hosts {
+		shards = append(shards, object.Sector{LatestHost: host})
+	}
+
+	return object.SlabSlice{Slab: object.Slab{Health: health, MinShards: minShards, Shards: shards}}
+}
+
+func TestScore(t *testing.T) {
+	known := map[types.PublicKey]struct{}{knownHost: {}}
+
+	tests := []struct {
+		name  string
+		slabs []object.SlabSlice
+		want  Score
+	}{
+		{"no slabs", nil, Score{}},
+		{"single slab", []object.SlabSlice{slab(0.5, 2, knownHost, unknownHost, unknownHost, knownHost)}, Score{Health: 0.5, Redundancy: 2, KnownHosts: 0.5}},
+		{"weakest slab wins", []object.SlabSlice{
+			slab(1, 1, knownHost, knownHost, knownHost),
+			slab(0.25, 2, unknownHost, unknownHost, unknownHost),
+		}, Score{Health: 0.25, Redundancy: 1.5, KnownHosts: 0.5}},
+		{"zero min shards counts as one", []object.SlabSlice{slab(1, 0, unknownHost, unknownHost)}, Score{Health: 1, Redundancy: 2}},
+		{"shardless slab", []object.SlabSlice{slab(1, 1)}, Score{Health: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm := FileMeta{Slabs: tt.slabs}
+
+			if got := fm.Score(known); got != tt.want {
+				t.Fatalf("Score = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreBetter(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Score
+		want bool
+	}{
+		{"health first", Score{Health: 1}, Score{Health: 0.5, Redundancy: 10, KnownHosts: 1}, true},
+		{"then redundancy", Score{Health: 1, Redundancy: 2}, Score{Health: 1, Redundancy: 1, KnownHosts: 1}, true},
+		{"then known hosts", Score{Health: 1, Redundancy: 2, KnownHosts: 1}, Score{Health: 1, Redundancy: 2}, true},
+		{"worse", Score{Health: 0.5}, Score{Health: 1}, false},
+		{"equal", Score{Health: 1, Redundancy: 1}, Score{Health: 1, Redundancy: 1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Better(tt.b); got != tt.want {
+				t.Fatalf("Better = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankFileMeta(t *testing.T) {
+	known := map[types.PublicKey]struct{}{knownHost: {}}
+
+	meta := []FileMeta{
+		{Protocol: "weak", Slabs: []object.SlabSlice{slab(0.2, 1, knownHost)}},
+		{Protocol: "unknown hosts", Slabs: []object.SlabSlice{slab(1, 1, unknownHost)}},
+		{Protocol: "known hosts", Slabs: []object.SlabSlice{slab(1, 1, knownHost)}},
+		{Protocol: "unknown hosts again", Slabs: []object.SlabSlice{slab(1, 1, unknownHost)}},
+		{Protocol: "redundant", Slabs: []object.SlabSlice{slab(1, 1, unknownHost, unknownHost)}},
+	}
+
+	RankFileMeta(meta, known)
+
+	want := []string{"redundant", "known hosts", "unknown hosts", "unknown hosts again", "weak"}
+	for i, m := range meta {
+		if m.Protocol != want[i] {
+			t.Fatalf("position %d is %q, want %q", i, m.Protocol, want[i])
+		}
+	}
+}
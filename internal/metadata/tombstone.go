@@ -0,0 +1,69 @@
+package metadata
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"go.sia.tech/renterd/object"
+)
+
+// TOMBSTONE_ALIAS marks a FileMeta as a tombstone. The sidecar wire format has no field for it, so it travels as a reserved alias.
+const TOMBSTONE_ALIAS = "sync:tombstone"
+
+// NewTombstone returns an entry that withdraws hash from the sync log. It carries a throwaway key so it round-trips through protobuf like any other entry.
+func NewTombstone(hash []byte, protocol string) FileMeta {
+	return FileMeta{
+		Hash:     hash,
+		Protocol: protocol,
+		Key:      object.GenerateEncryptionKey(),
+		Aliases:  []string{TOMBSTONE_ALIAS},
+	}
+}
+
+func (fm *FileMeta) IsTombstone() bool {
+	for _, alias := range fm.Aliases {
+		if alias == TOMBSTONE_ALIAS {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FilterTombstoned drops tombstones, and every entry that the writer of a tombstone wrote for the same hash. Entries for that hash from other writers are kept, since one portal deleting its copy says nothing about anyone else's.
+//
+// The node backend does not report writers, so a tombstone without one cannot be matched to the entries it replaces. It withdraws every entry for its hash instead.
+func FilterTombstoned(meta []*FileMeta) []*FileMeta {
+	removed := make(map[string]struct{})
+	for _, m := range meta {
+		if m.IsTombstone() {
+			removed[tombstoneKey(m.Hash, m.Writer)] = struct{}{}
+		}
+	}
+
+	if len(removed) == 0 {
+		return meta
+	}
+
+	filtered := make([]*FileMeta, 0, len(meta))
+	for _, m := range meta {
+		if m.IsTombstone() {
+			continue
+		}
+
+		if _, ok := removed[tombstoneKey(m.Hash, nil)]; ok {
+			continue
+		}
+
+		if _, ok := removed[tombstoneKey(m.Hash, m.Writer)]; ok && len(m.Writer) > 0 {
+			continue
+		}
+
+		filtered = append(filtered, m)
+	}
+
+	return filtered
+}
+
+func tombstoneKey(hash []byte, writer ed25519.PublicKey) string {
+	return hex.EncodeToString(hash) + ":" + hex.EncodeToString(writer)
+}
@@ -0,0 +1,64 @@
+package metadata
+
+import (
+	"crypto/ed25519"
+	"testing"
+)
+
+func TestFilterTombstonedKeepsOtherWriters(t *testing.T) {
+	hash := []byte{1, 2, 3}
+	a := ed25519.PublicKey{1}
+	b := ed25519.PublicKey{2}
+
+	tombstone := NewTombstone(hash, "")
+	tombstone.Writer = a
+
+	meta := []*FileMeta{
+		{Hash: hash, Writer: a},
+		{Hash: hash, Writer: b},
+		{Hash: []byte{4}, Writer: a},
+		&tombstone,
+	}
+
+	filtered := FilterTombstoned(meta)
+
+	if len(filtered) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(filtered))
+	}
+
+	if filtered[0] != meta[1] || filtered[1] != meta[2] {
+		t.Fatal("expected the other writer's entry and the unrelated hash to be kept")
+	}
+}
+
+func TestFilterTombstoned(t *testing.T) {
+	hash := []byte{1, 2, 3}
+	other := []byte{4}
+	a := ed25519.PublicKey{1}
+	b := ed25519.PublicKey{2}
+
+	tombstone := func(writer ed25519.PublicKey) *FileMeta {
+		m := NewTombstone(hash, "")
+		m.Writer = writer
+		return &m
+	}
+
+	tests := []struct {
+		name string
+		meta []*FileMeta
+		want int
+	}{
+		{"no tombstones", []*FileMeta{{Hash: hash}, {Hash: other}}, 2},
+		{"unattributed tombstone withdraws the hash", []*FileMeta{{Hash: hash}, {Hash: hash, Writer: b}, {Hash: other}, tombstone(nil)}, 1},
+		{"attributed tombstone keeps unattributed entries", []*FileMeta{{Hash: hash}, {Hash: hash, Writer: a}, tombstone(a)}, 1},
+		{"tombstone alone", []*FileMeta{tombstone(a)}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FilterTombstoned(tt.meta); len(got) != tt.want {
+				t.Fatalf("kept %d entries, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
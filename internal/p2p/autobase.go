@@ -1,10 +1,19 @@
 package sync
 
 import (
+	"bytes"
 	"crypto/ed25519"
+	"fmt"
 	"math"
+	"slices"
 )
 
+// VIEW_NAME is the name of the autobase view holding the sync log.
+const VIEW_NAME = "autobee"
+
+// SYSTEM_CORE_NAME is the name of the core autobase keeps its own system state in.
+const SYSTEM_CORE_NAME = "_system"
+
 var (
 	NS_SIGNER_NAMESPACE []byte
 	NS_VIEW_BLOCK_KEY   []byte
@@ -17,23 +26,47 @@ func init() {
 }
 
 func createAutobaseManifest(name string, bootstrapNode ed25519.PublicKey) *manifest {
-	bootstrapManifest, _ := nodeKeyManifest(bootstrapNode, map[string]interface{}{})
-	signers := []signer{
-		{
-			PublicKey: bootstrapNode,
+	m, _ := createIndexerManifest(name, bootstrapNode, []ed25519.PublicKey{bootstrapNode}, 0)
+	return m
+}
+
+// createIndexerManifest builds the manifest of the autobase core name, signed by every indexer. Each signer gets its own namespace derived from the bootstrap key, as autobase does. A quorum of 0 requires a majority of the indexers.
+func createIndexerManifest(name string, bootstrapNode ed25519.PublicKey, indexers []ed25519.PublicKey, quorum int) (*manifest, error) {
+	if len(indexers) == 0 {
+		return nil, errNoSigners
+	}
+
+	if quorum == 0 {
+		quorum = int(math.Min(float64(len(indexers)), float64(len(indexers)>>1)) + 1)
+	}
+
+	if quorum < 0 || quorum > len(indexers) {
+		return nil, fmt.Errorf("%w: %d of %d indexers", errInvalidQuorum, quorum, len(indexers))
+	}
+
+	signers := make([]signer, 0, len(indexers))
+
+	for _, indexer := range indexers {
+		indexerManifest, err := nodeKeyManifest(indexer, map[string]interface{}{})
+		if err != nil {
+			return nil, err
+		}
+
+		signers = append(signers, signer{
+			PublicKey: indexer,
 			Signature: "ed25519",
-			Namespace: deriveAutobaseNamespace(name, bootstrapManifest.Signers[0].Namespace, bootstrapNode),
-		},
+			Namespace: deriveAutobaseNamespace(name, indexerManifest.Signers[0].Namespace, bootstrapNode),
+		})
 	}
 
 	return &manifest{
 		Version:    1,
 		Hash:       "blake2b",
 		AllowPatch: true,
-		Quorum:     int(math.Min(float64(len(signers)), float64(len(signers)>>1)) + 1),
+		Quorum:     quorum,
 		Signers:    signers,
 		Prologue:   nil,
-	}
+	}, nil
 }
 
 func deriveAutobaseNamespace(name string, entropy []byte, bootstrap []byte) []byte {
@@ -53,7 +86,84 @@ func deriveAutobaseNamespace(name string, entropy []byte, bootstrap []byte) []by
 
 	return hash(buf, nil)
 }
-func AutoBaseKey(bootstrapNode ed25519.PublicKey) ed25519.PublicKey {
-	m := createAutobaseManifest("autobee", bootstrapNode)
+func AutoBaseKey(bootstrapNode ed25519.PublicKey) (ed25519.PublicKey, error) {
+	return CoreKey(VIEW_NAME, bootstrapNode)
+}
+
+// CoreKey derives the key of the autobase core name while bootstrapNode is its only indexer. Use NewView for cores signed by several indexers.
+func CoreKey(name string, bootstrapNode ed25519.PublicKey) (ed25519.PublicKey, error) {
+	m, err := createIndexerManifest(name, bootstrapNode, []ed25519.PublicKey{bootstrapNode}, 0)
+	if err != nil {
+		return nil, err
+	}
+
 	return manifestHash(m)
 }
+
+// SystemCoreKey derives the key of the autobase system core.
+func SystemCoreKey(bootstrapNode ed25519.PublicKey) (ed25519.PublicKey, error) {
+	return CoreKey(SYSTEM_CORE_NAME, bootstrapNode)
+}
+
+// ViewCoreKeys derives the key of every named view core.
+func ViewCoreKeys(bootstrapNode ed25519.PublicKey, names ...string) (map[string]ed25519.PublicKey, error) {
+	keys := make(map[string]ed25519.PublicKey, len(names))
+
+	for _, name := range names {
+		key, err := CoreKey(name, bootstrapNode)
+		if err != nil {
+			return nil, fmt.Errorf("view %s: %w", name, err)
+		}
+		keys[name] = key
+	}
+
+	return keys, nil
+}
+
+// View describes an autobase view core: its key, the indexers that sign it and how many of them must sign for the view to advance.
+type View struct {
+	Name    string
+	Key     ed25519.PublicKey
+	Quorum  int
+	Signers []ed25519.PublicKey
+}
+
+// NewView derives the view core name signed by indexers. Indexers are sorted by key so every node derives the same manifest regardless of the order it learned them in. A quorum of 0 requires a majority of the indexers.
+func NewView(name string, bootstrapNode ed25519.PublicKey, indexers []ed25519.PublicKey, quorum int) (*View, error) {
+	sorted := slices.Clone(indexers)
+	slices.SortFunc(sorted, func(a, b ed25519.PublicKey) int {
+		return bytes.Compare(a, b)
+	})
+	sorted = slices.CompactFunc(sorted, func(a, b ed25519.PublicKey) bool {
+		return bytes.Equal(a, b)
+	})
+
+	m, err := createIndexerManifest(name, bootstrapNode, sorted, quorum)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := manifestHash(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return &View{
+		Name:    name,
+		Key:     key,
+		Quorum:  m.Quorum,
+		Signers: sorted,
+	}, nil
+}
+
+// Writable reports whether enough of the view's signers are live to reach quorum.
+func (v *View) Writable(live func(ed25519.PublicKey) bool) bool {
+	count := 0
+	for _, s := range v.Signers {
+		if live(s) {
+			count++
+		}
+	}
+
+	return count >= v.Quorum
+}
@@ -0,0 +1,88 @@
+package sync
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+)
+
+func TestNewViewQuorum(t *testing.T) {
+	bootstrap := ed25519.PublicKey(fill(1))
+	indexers := []ed25519.PublicKey{fill(3), fill(1), fill(2), fill(1)}
+
+	tests := []struct {
+		name   string
+		quorum int
+		want   int
+		err    error
+	}{
+		{"majority", 0, 2, nil},
+		{"one", 1, 1, nil},
+		{"all", 3, 3, nil},
+		{"above indexers", 4, 0, errInvalidQuorum},
+		{"negative", -1, 0, errInvalidQuorum},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view, err := NewView(VIEW_NAME, bootstrap, indexers, tt.quorum)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if view.Quorum != tt.want {
+				t.Fatalf("quorum = %d, want %d", view.Quorum, tt.want)
+			}
+
+			if len(view.Signers) != 3 {
+				t.Fatalf("expected duplicate indexers to be dropped, got %d signers", len(view.Signers))
+			}
+		})
+	}
+}
+
+func TestNewViewKey(t *testing.T) {
+	bootstrap := ed25519.PublicKey(fill(1))
+
+	a, err := NewView(VIEW_NAME, bootstrap, []ed25519.PublicKey{fill(1), fill(2)}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := NewView(VIEW_NAME, bootstrap, []ed25519.PublicKey{fill(2), fill(1)}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(a.Key, b.Key) {
+		t.Fatal("indexer order changed the view key")
+	}
+
+	c, err := NewView(VIEW_NAME, bootstrap, []ed25519.PublicKey{fill(1), fill(2)}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Equal(a.Key, c.Key) {
+		t.Fatal("quorum is not part of the view key")
+	}
+
+	single, err := NewView(VIEW_NAME, bootstrap, []ed25519.PublicKey{bootstrap}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := AutoBaseKey(bootstrap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(single.Key, key) {
+		t.Fatal("a view indexed by the bootstrap node alone should have the autobase key")
+	}
+}
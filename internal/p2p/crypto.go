@@ -1,11 +1,21 @@
 package sync
 
 import (
-	"encoding/hex"
-	"fmt"
+	"crypto/ed25519"
 	"golang.org/x/crypto/blake2b"
 )
 
+// HYPERCORE is the message hashed, keyed by a core's public key, to derive the core's discovery key.
+var HYPERCORE = []byte("hypercore")
+
+// DiscoveryKey returns the key peers announce and look up on the DHT to find a core, without revealing the core key itself.
+func DiscoveryKey(publicKey ed25519.PublicKey) []byte {
+	out := make([]byte, 32)
+	cryptoGenericHash(out, HYPERCORE, publicKey)
+
+	return out
+}
+
 func namespace(name string, count interface{}) [][]byte {
 	var ids []int
 	switch v := count.(type) {
@@ -35,95 +45,14 @@ func namespace(name string, count interface{}) [][]byte {
 	return list
 }
 
-func manifestHash(manifest *manifest) []byte {
-	if manifest.Version == 0 {
-		return manifestHashv0(manifest)
-	}
-
-	buffer := make([]byte, 102)
-	offset := 0
-
-	// Write the manifest prefix
-	copy(buffer[offset:32], MANIFEST)
-	offset += 32
-
-	// Write the version (assuming version is always 1)
-	buffer[offset] = 1
-	offset++
-
-	flags := 0
-	if manifest.AllowPatch {
-		flags |= 1
-	}
-	if manifest.Prologue != nil {
-		flags |= 2
-	}
-
-	// Write the flags
-	buffer[offset] = byte(flags)
-	offset++
-
-	// Write the hash type (assuming hash type is always blake2b)
-	buffer[offset] = 0
-	offset++
-
-	// Write the quorum (assuming quorum is always less than or equal to 0xfc)
-	buffer[offset] = byte(manifest.Quorum)
-	offset++
-
-	buffer[offset] = byte(len(manifest.Signers))
-	offset++
-	// Write the signers (assuming signers is an array of Signer structs)
-	for _, signer := range manifest.Signers {
-		buffer[offset+1] = 0
-		offset++
-		// Write the namespace (assuming namespace is a 32-byte array)
-		copy(buffer[offset:offset+32], signer.Namespace[:])
-		offset += 32
-
-		// Write the public key (assuming public key is a 32-byte array)
-		copy(buffer[offset:offset+32], signer.PublicKey[:])
-		offset += 32
-	}
-
-	fmt.Println(hex.EncodeToString(buffer))
-
-	return hash(buffer, nil)
-}
-
-func manifestHashv0(manifest *manifest) []byte {
-	buffer := make([]byte, 100)
-	offset := 0
-
-	// Write the manifest prefix
-	copy(buffer[offset:32], MANIFEST)
-	offset += 32
-
-	// Write the version (assuming version is always 1)
-	buffer[offset] = 0
-	offset++
-
-	// Write the hash type (assuming hash type is always blake2b)
-	buffer[offset] = 0
-	offset++
-
-	// Write the quorum (assuming quorum is always less than or equal to 0xfc)
-	buffer[offset] = byte(manifest.Quorum)
-	offset++
-	// Write the signers (assuming signers is an array of Signer structs)
-	for _, signer := range manifest.Signers {
-		buffer[offset+1] = 0
-		offset++
-		// Write the namespace (assuming namespace is a 32-byte array)
-		copy(buffer[offset:offset+32], signer.Namespace[:])
-		offset += 32
-
-		// Write the public key (assuming public key is a 32-byte array)
-		copy(buffer[offset:offset+32], signer.PublicKey[:])
-		offset += 32
+// manifestHash returns the core key for m, the blake2b hash of the MANIFEST namespace followed by the encoded manifest.
+func manifestHash(m *manifest) ([]byte, error) {
+	encoded, err := encodeManifest(m)
+	if err != nil {
+		return nil, err
 	}
 
-	return hash(buffer, nil)
+	return hash([][]byte{MANIFEST, encoded}, nil), nil
 }
 
 func hash(data interface{}, out []byte) []byte {
@@ -0,0 +1,42 @@
+package sync
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// encoder writes Hypercore's compact-encoding format.
+type encoder struct {
+	buf []byte
+}
+
+// uint writes n using the compact-encoding variable length format: values below 0xfd take a single byte, larger ones a marker byte followed by 2, 4 or 8 little-endian bytes.
+func (e *encoder) uint(n uint64) {
+	switch {
+	case n <= 0xfc:
+		e.buf = append(e.buf, byte(n))
+	case n <= 0xffff:
+		e.buf = append(e.buf, 0xfd)
+		e.buf = binary.LittleEndian.AppendUint16(e.buf, uint16(n))
+	case n <= 0xffffffff:
+		e.buf = append(e.buf, 0xfe)
+		e.buf = binary.LittleEndian.AppendUint32(e.buf, uint32(n))
+	default:
+		e.buf = append(e.buf, 0xff)
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, n)
+	}
+}
+
+func (e *encoder) fixed32(b []byte) error {
+	if len(b) != 32 {
+		return fmt.Errorf("%w: expected 32 bytes, got %d", errInvalidFixed32, len(b))
+	}
+
+	e.buf = append(e.buf, b...)
+
+	return nil
+}
+
+func (e *encoder) bytes() []byte {
+	return e.buf
+}
@@ -0,0 +1,45 @@
+package sync
+
+import "math/bits"
+
+// Hypercore lays its merkle tree out as a flat tree: leaves sit at even indexes, and a node at depth d covering 2^d
+// leaves sits halfway between the first and last of them.
+
+func flatDepth(index uint64) uint64 {
+	return uint64(bits.TrailingZeros64(^index))
+}
+
+func flatOffset(index uint64) uint64 {
+	return index >> (flatDepth(index) + 1)
+}
+
+func flatIndex(depth uint64, offset uint64) uint64 {
+	return (1+2*offset)<<depth - 1
+}
+
+// FlatParent returns the index of the node above index.
+func FlatParent(index uint64) uint64 {
+	depth := flatDepth(index)
+	return flatIndex(depth+1, flatOffset(index)>>1)
+}
+
+// FlatSibling returns the index of the node that shares index's parent.
+func FlatSibling(index uint64) uint64 {
+	depth := flatDepth(index)
+	return flatIndex(depth, flatOffset(index)^1)
+}
+
+// FlatFullRoots returns the roots of a tree with the given number of leaves, from left to right.
+func FlatFullRoots(leaves uint64) []uint64 {
+	var roots []uint64
+
+	offset := uint64(0)
+	for leaves > 0 {
+		factor := uint64(1) << (63 - bits.LeadingZeros64(leaves))
+		roots = append(roots, offset+factor-1)
+		offset += 2 * factor
+		leaves -= factor
+	}
+
+	return roots
+}
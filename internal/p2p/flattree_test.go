@@ -0,0 +1,55 @@
+package sync
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFlatTree(t *testing.T) {
+	for _, tt := range []struct{ index, depth, offset uint64 }{
+		{0, 0, 0}, {1, 1, 0}, {2, 0, 1}, {3, 2, 0}, {5, 1, 1}, {7, 3, 0}, {11, 2, 1}, {23, 3, 1},
+	} {
+		if got := flatDepth(tt.index); got != tt.depth {
+			t.Errorf("depth(%d) = %d, want %d", tt.index, got, tt.depth)
+		}
+
+		if got := flatOffset(tt.index); got != tt.offset {
+			t.Errorf("offset(%d) = %d, want %d", tt.index, got, tt.offset)
+		}
+
+		if got := flatIndex(tt.depth, tt.offset); got != tt.index {
+			t.Errorf("index(%d, %d) = %d, want %d", tt.depth, tt.offset, got, tt.index)
+		}
+	}
+
+	for _, tt := range []struct{ index, parent, sibling uint64 }{
+		{0, 1, 2}, {2, 1, 0}, {4, 5, 6}, {1, 3, 5}, {5, 3, 1}, {3, 7, 11}, {9, 11, 13}, {11, 7, 3},
+	} {
+		if got := FlatParent(tt.index); got != tt.parent {
+			t.Errorf("parent(%d) = %d, want %d", tt.index, got, tt.parent)
+		}
+
+		if got := FlatSibling(tt.index); got != tt.sibling {
+			t.Errorf("sibling(%d) = %d, want %d", tt.index, got, tt.sibling)
+		}
+	}
+}
+
+func TestFlatFullRoots(t *testing.T) {
+	for _, tt := range []struct {
+		leaves uint64
+		roots  []uint64
+	}{
+		{0, nil},
+		{1, []uint64{0}},
+		{2, []uint64{1}},
+		{3, []uint64{1, 4}},
+		{5, []uint64{3, 8}},
+		{8, []uint64{7}},
+		{11, []uint64{7, 17, 20}},
+	} {
+		if got := FlatFullRoots(tt.leaves); !slices.Equal(got, tt.roots) {
+			t.Errorf("fullRoots(%d) = %v, want %v", tt.leaves, got, tt.roots)
+		}
+	}
+}
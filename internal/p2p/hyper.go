@@ -7,12 +7,22 @@ import (
 )
 
 var (
+	TREE              []byte
 	MANIFEST          []byte
 	DEFAULT_NAMESPACE []byte
 )
 
+var (
+	errInvalidOption    = errors.New("invalid manifest option")
+	errInvalidSigner    = errors.New("invalid signer")
+	errInvalidPrologue  = errors.New("invalid prologue")
+	errMissingPublicKey = errors.New("signer missing public key")
+	errNilManifest      = errors.New("manifest is nil")
+)
+
 func init() {
 	list := namespace("hypercore", 6)
+	TREE = list[0]
 	MANIFEST = list[3]
 	DEFAULT_NAMESPACE = list[4]
 }
@@ -37,159 +47,393 @@ type Prologue struct {
 	Length int
 }
 
-func createManifest(inp map[string]interface{}) (*manifest, error) {
-	if inp == nil {
-		return nil, nil
+// SignerSpec describes one signer of a manifest. Signature may be left empty for ed25519, the only scheme supported, and a nil Namespace uses DEFAULT_NAMESPACE.
+type SignerSpec struct {
+	Signature string
+	Namespace []byte
+	PublicKey ed25519.PublicKey
+}
+
+// ManifestOptions describes a Hypercore manifest. A nil Version uses version 1 and a nil Quorum a majority of the signers, as Hypercore does. Hash may be left empty for blake2b.
+type ManifestOptions struct {
+	Version    *int
+	Hash       string
+	AllowPatch bool
+	Quorum     *int
+	Signers    []SignerSpec
+	Prologue   *Prologue
+}
+
+// NodeKeyOptions controls how a core key is derived from a single public key. Compat returns the public key itself, as cores created before manifests existed used it directly. Version is 0 unless set, which every key this plugin has derived so far uses.
+type NodeKeyOptions struct {
+	Version   int
+	Namespace []byte
+	Compat    bool
+}
+
+func (s SignerSpec) validate() error {
+	if len(s.PublicKey) == 0 {
+		return errMissingPublicKey
 	}
 
-	manifest := &manifest{
-		Version:    1,
-		Hash:       "blake2b",
-		AllowPatch: false,
-		Quorum:     defaultQuorum(inp),
-		Signers:    []signer{},
-		Prologue:   nil,
+	if len(s.PublicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: public key must be %d bytes, got %d", errInvalidSigner, ed25519.PublicKeySize, len(s.PublicKey))
 	}
 
-	if version, ok := inp["version"].(int); ok {
-		manifest.Version = version
+	if s.Signature != "" && s.Signature != "ed25519" {
+		return errUnsupportedSignature
 	}
 
-	if allowPatch, ok := inp["allowPatch"].(bool); ok {
-		manifest.AllowPatch = allowPatch
+	if s.Namespace != nil && len(s.Namespace) != 32 {
+		return fmt.Errorf("%w: namespace must be 32 bytes, got %d", errInvalidSigner, len(s.Namespace))
 	}
 
-	if hash, ok := inp["hash"].(string); ok {
-		if hash != "blake2b" {
-			return nil, errors.New("Only Blake2b hashes are supported")
-		}
-		manifest.Hash = hash
+	return nil
+}
+
+func (o ManifestOptions) version() int {
+	if o.Version == nil {
+		return 1
 	}
 
-	if signers, ok := inp["signers"].([]map[string]interface{}); ok {
-		for _, signer := range signers {
-			parsedSigner, err := parseSigner(signer)
-			if err != nil {
-				return nil, err
-			}
-			manifest.Signers = append(manifest.Signers, parsedSigner)
+	return *o.Version
+}
+
+func (o ManifestOptions) quorum() int {
+	if o.Quorum != nil {
+		return *o.Quorum
+	}
+
+	if len(o.Signers) == 0 {
+		return 0
+	}
+
+	return (len(o.Signers) >> 1) + 1
+}
+
+func (o ManifestOptions) validate() error {
+	if version := o.version(); version != 0 && version != 1 {
+		return fmt.Errorf("%w: %d", errUnsupportedVersion, version)
+	}
+
+	if o.Hash != "" && o.Hash != "blake2b" {
+		return errUnsupportedHash
+	}
+
+	for i, s := range o.Signers {
+		if err := s.validate(); err != nil {
+			return fmt.Errorf("signer %d: %w", i, err)
 		}
 	}
 
-	if prologue, ok := inp["prologue"].(map[string]interface{}); ok {
-		hash, ok := prologue["hash"].([]byte)
-		if !ok || len(hash) != 32 {
-			return nil, errors.New("Invalid prologue")
+	if quorum := o.quorum(); quorum < 0 || quorum > len(o.Signers) {
+		return fmt.Errorf("%w: %d of %d signers", errInvalidQuorum, quorum, len(o.Signers))
+	}
+
+	if o.Prologue != nil {
+		if o.version() == 0 && len(o.Signers) > 0 {
+			return fmt.Errorf("%w: version 0 manifests can only have a prologue without signers", errInvalidPrologue)
 		}
 
-		length, ok := prologue["length"].(int)
-		if !ok || length < 0 {
-			return nil, errors.New("Invalid prologue")
+		if len(o.Prologue.Hash) != 32 || o.Prologue.Length < 0 {
+			return errInvalidPrologue
 		}
+	}
 
-		manifest.Prologue = &Prologue{
-			Hash:   hash,
-			Length: length,
+	return nil
+}
+
+func newManifest(opts ManifestOptions) (*manifest, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	m := &manifest{
+		Version:    opts.version(),
+		Hash:       "blake2b",
+		AllowPatch: opts.AllowPatch,
+		Quorum:     opts.quorum(),
+		Signers:    make([]signer, 0, len(opts.Signers)),
+		Prologue:   opts.Prologue,
+	}
+
+	for _, s := range opts.Signers {
+		namespace := s.Namespace
+		if namespace == nil {
+			namespace = DEFAULT_NAMESPACE
 		}
+
+		m.Signers = append(m.Signers, signer{
+			Signature: "ed25519",
+			Namespace: namespace,
+			PublicKey: s.PublicKey,
+		})
+	}
+
+	return m, nil
+}
+
+// ManifestKey returns the core key of the manifest described by opts.
+func ManifestKey(opts ManifestOptions) (ed25519.PublicKey, error) {
+	m, err := newManifest(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return manifestHash(m)
+}
+
+// PublicKeyNodeKey returns the key of a core signed by publicKey alone.
+func PublicKeyNodeKey(publicKey ed25519.PublicKey, opts NodeKeyOptions) (ed25519.PublicKey, error) {
+	spec := SignerSpec{PublicKey: publicKey, Namespace: opts.Namespace}
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+
+	if opts.Compat {
+		return publicKey, nil
+	}
+
+	return ManifestKey(ManifestOptions{
+		Version: &opts.Version,
+		Signers: []SignerSpec{spec},
+	})
+}
+
+// createManifest builds a manifest from its map form. It is kept for callers of the map based API, new code should use ManifestOptions.
+func createManifest(inp map[string]interface{}) (*manifest, error) {
+	if inp == nil {
+		return nil, errNilManifest
+	}
+
+	opts, err := manifestOptionsFromMap(inp)
+	if err != nil {
+		return nil, err
 	}
 
-	return manifest, nil
+	return newManifest(opts)
 }
 
+// NodeKey derives a core key from either a public key or a manifest in map form. It is a shim over PublicKeyNodeKey and ManifestKey for the map based API; wrongly typed options are reported as errors.
 func NodeKey(manifest interface{}, opts map[string]interface{}) (ed25519.PublicKey, error) {
-	compat, _ := opts["compat"].(bool)
+	nodeOpts, err := nodeKeyOptionsFromMap(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	m, err := nodeKeyManifest(manifest, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	if compat {
+	if nodeOpts.Compat {
+		if len(m.Signers) == 0 {
+			return nil, errNoSigners
+		}
 		return m.Signers[0].PublicKey, nil
 	}
 
-	return manifestHash(m), nil
+	return manifestHash(m)
 }
 
 func nodeKeyManifest(manifest interface{}, opts map[string]interface{}) (*manifest, error) {
-	version, _ := opts["version"].(int)
-	_namespace, _ := opts["namespace"].([]byte)
-
-	var manifestMap map[string]interface{}
-
-	if buf, ok := manifest.(ed25519.PublicKey); ok {
-		manifestMap = map[string]interface{}{
-			"version": version,
-			"signers": []map[string]interface{}{{
-				"publicKey": buf,
-				"namespace": _namespace,
-			}},
-		}
-	} else {
-		var ok bool
-		manifestMap, ok = manifest.(map[string]interface{})
-		if !ok {
-			return nil, fmt.Errorf("invalid manifest type")
-		}
-	}
-
-	m, err := createManifest(manifestMap)
+	nodeOpts, err := nodeKeyOptionsFromMap(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return m, nil
+	switch v := manifest.(type) {
+	case ed25519.PublicKey:
+		return newManifest(ManifestOptions{
+			Version: &nodeOpts.Version,
+			Signers: []SignerSpec{{PublicKey: v, Namespace: nodeOpts.Namespace}},
+		})
+	case []byte:
+		return nodeKeyManifest(ed25519.PublicKey(v), opts)
+	case ManifestOptions:
+		return newManifest(v)
+	case map[string]interface{}:
+		return createManifest(v)
+	default:
+		return nil, fmt.Errorf("invalid manifest type %T", manifest)
+	}
 }
 
-func defaultQuorum(inp map[string]interface{}) int {
-	if quorum, ok := inp["quorum"].(int); ok {
-		return quorum
+func nodeKeyOptionsFromMap(opts map[string]interface{}) (NodeKeyOptions, error) {
+	var nodeOpts NodeKeyOptions
+	var err error
+
+	if nodeOpts.Version, _, err = optInt(opts, "version"); err != nil {
+		return NodeKeyOptions{}, err
 	}
 
-	signers, ok := inp["signers"].([]map[string]interface{})
-	if !ok || len(signers) == 0 {
-		return 0
+	if nodeOpts.Namespace, err = optBytes(opts, "namespace"); err != nil {
+		return NodeKeyOptions{}, err
 	}
 
-	return (len(signers) >> 1) + 1
+	if nodeOpts.Compat, err = optBool(opts, "compat"); err != nil {
+		return NodeKeyOptions{}, err
+	}
+
+	return nodeOpts, nil
 }
 
-func parseSigner(_signer interface{}) (signer, error) {
-	signerMap, ok := _signer.(map[string]interface{})
-	if !ok {
-		return signer{}, errors.New("Invalid signer format")
+func manifestOptionsFromMap(inp map[string]interface{}) (ManifestOptions, error) {
+	var opts ManifestOptions
+
+	version, ok, err := optInt(inp, "version")
+	if err != nil {
+		return ManifestOptions{}, err
+	}
+	if ok {
+		opts.Version = &version
+	}
+
+	if opts.AllowPatch, err = optBool(inp, "allowPatch"); err != nil {
+		return ManifestOptions{}, err
 	}
 
-	err := validateSigner(signerMap)
+	quorum, ok, err := optInt(inp, "quorum")
 	if err != nil {
-		return signer{}, err
+		return ManifestOptions{}, err
+	}
+	if ok {
+		opts.Quorum = &quorum
 	}
 
-	namespace, _ := signerMap["namespace"].([]byte)
-	if namespace == nil {
-		namespace = DEFAULT_NAMESPACE
+	if hash, ok := inp["hash"]; ok && hash != nil {
+		opts.Hash, ok = hash.(string)
+		if !ok {
+			return ManifestOptions{}, fmt.Errorf("%w: hash must be a string, got %T", errInvalidOption, hash)
+		}
 	}
 
-	return signer{
-		Signature: "ed25519",
+	if signers, ok := inp["signers"]; ok && signers != nil {
+		var list []map[string]interface{}
+
+		switch v := signers.(type) {
+		case []map[string]interface{}:
+			list = v
+		case []interface{}:
+			for i, s := range v {
+				signerMap, ok := s.(map[string]interface{})
+				if !ok {
+					return ManifestOptions{}, fmt.Errorf("signer %d: %w: expected a map, got %T", i, errInvalidSigner, s)
+				}
+				list = append(list, signerMap)
+			}
+		default:
+			return ManifestOptions{}, fmt.Errorf("%w: signers must be a list, got %T", errInvalidOption, signers)
+		}
+
+		for i, signerMap := range list {
+			spec, err := signerSpecFromMap(signerMap)
+			if err != nil {
+				return ManifestOptions{}, fmt.Errorf("signer %d: %w", i, err)
+			}
+			opts.Signers = append(opts.Signers, spec)
+		}
+	}
+
+	if prologue, ok := inp["prologue"]; ok && prologue != nil {
+		prologueMap, ok := prologue.(map[string]interface{})
+		if !ok {
+			return ManifestOptions{}, fmt.Errorf("%w: expected a map, got %T", errInvalidPrologue, prologue)
+		}
+
+		hash, err := optBytes(prologueMap, "hash")
+		if err != nil {
+			return ManifestOptions{}, err
+		}
+
+		length, ok, err := optInt(prologueMap, "length")
+		if err != nil {
+			return ManifestOptions{}, err
+		}
+		if !ok {
+			return ManifestOptions{}, fmt.Errorf("%w: missing length", errInvalidPrologue)
+		}
+
+		opts.Prologue = &Prologue{
+			Hash:   hash,
+			Length: length,
+		}
+	}
+
+	return opts, nil
+}
+
+func signerSpecFromMap(signerMap map[string]interface{}) (SignerSpec, error) {
+	if signerMap == nil {
+		return SignerSpec{}, fmt.Errorf("%w: signer is nil", errInvalidSigner)
+	}
+
+	publicKey, err := optBytes(signerMap, "publicKey")
+	if err != nil {
+		return SignerSpec{}, err
+	}
+
+	namespace, err := optBytes(signerMap, "namespace")
+	if err != nil {
+		return SignerSpec{}, err
+	}
+
+	spec := SignerSpec{
+		PublicKey: publicKey,
 		Namespace: namespace,
-		PublicKey: signerMap["publicKey"].(ed25519.PublicKey),
-	}, nil
+	}
+
+	if signature, ok := signerMap["signature"]; ok && signature != nil {
+		spec.Signature, ok = signature.(string)
+		if !ok {
+			return SignerSpec{}, fmt.Errorf("%w: signature must be a string, got %T", errInvalidSigner, signature)
+		}
+	}
+
+	return spec, spec.validate()
 }
 
-func validateSigner(signer map[string]interface{}) error {
-	if signer == nil {
-		return errors.New("Signer is nil")
+func optInt(opts map[string]interface{}, key string) (int, bool, error) {
+	v, ok := opts[key]
+	if !ok || v == nil {
+		return 0, false, nil
 	}
 
-	publicKey, ok := signer["publicKey"].(ed25519.PublicKey)
-	if !ok || len(publicKey) == 0 {
-		return errors.New("Signer missing public key")
+	i, ok := v.(int)
+	if !ok {
+		return 0, false, fmt.Errorf("%w: %s must be an int, got %T", errInvalidOption, key, v)
 	}
 
-	signature, _ := signer["signature"].(string)
-	if signature != "" && signature != "ed25519" {
-		return errors.New("Only Ed25519 signatures are supported")
+	return i, true, nil
+}
+
+func optBool(opts map[string]interface{}, key string) (bool, error) {
+	v, ok := opts[key]
+	if !ok || v == nil {
+		return false, nil
 	}
 
-	return nil
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%w: %s must be a bool, got %T", errInvalidOption, key, v)
+	}
+
+	return b, nil
+}
+
+// optBytes reads a byte slice option, accepting ed25519.PublicKey as well as []byte.
+func optBytes(opts map[string]interface{}, key string) ([]byte, error) {
+	v, ok := opts[key]
+	if !ok || v == nil {
+		return nil, nil
+	}
+
+	switch b := v.(type) {
+	case []byte:
+		return b, nil
+	case ed25519.PublicKey:
+		return b, nil
+	default:
+		return nil, fmt.Errorf("%w: %s must be bytes, got %T", errInvalidOption, key, v)
+	}
 }
@@ -0,0 +1,227 @@
+package sync
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"github.com/samber/lo"
+	"testing"
+)
+
+func testPublicKey(b byte) ed25519.PublicKey {
+	return ed25519.PublicKey(fill(b))
+}
+
+func TestNamespace(t *testing.T) {
+	// hyperswarm/secret-stream's handshake namespace, as published by the JS implementation
+	want := "742c9d833d430af4c48a8705e91631eecf295442bbca18996e597097723b1061"
+
+	if got := hex.EncodeToString(namespace("hyperswarm/secret-stream", 2)[1]); got != want {
+		t.Fatalf("namespace = %s, want %s", got, want)
+	}
+
+	list := namespace("hypercore", []int{3})
+	if !bytes.Equal(list[0], MANIFEST) {
+		t.Fatalf("namespace with explicit ids = %x, want %x", list[0], MANIFEST)
+	}
+}
+
+func TestNodeKeyCompat(t *testing.T) {
+	key := testPublicKey(1)
+
+	got, err := NodeKey(key, map[string]interface{}{"compat": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, key) {
+		t.Fatalf("compat key = %x, want the public key", got)
+	}
+
+	got, err = PublicKeyNodeKey(key, NodeKeyOptions{Compat: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, key) {
+		t.Fatalf("compat key = %x, want the public key", got)
+	}
+
+	if _, err := NodeKey(map[string]interface{}{}, map[string]interface{}{"compat": true}); !errors.Is(err, errNoSigners) {
+		t.Fatalf("expected errNoSigners, got %v", err)
+	}
+}
+
+func TestNodeKeyVersion(t *testing.T) {
+	key := testPublicKey(1)
+	signers := []SignerSpec{{PublicKey: key}}
+
+	v0, err := ManifestKey(ManifestOptions{Version: lo.ToPtr(0), Signers: signers})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v1, err := ManifestKey(ManifestOptions{Signers: signers})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Equal(v0, v1) {
+		t.Fatal("version 0 and 1 manifests hash to the same key")
+	}
+
+	tests := []struct {
+		name string
+		opts map[string]interface{}
+		want ed25519.PublicKey
+	}{
+		{"default", nil, v0},
+		{"version 0", map[string]interface{}{"version": 0}, v0},
+		{"version 1", map[string]interface{}{"version": 1}, v1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NodeKey(key, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, tt.want) {
+				t.Fatalf("NodeKey = %x, want %x", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NodeKey(key, map[string]interface{}{"version": 2}); !errors.Is(err, errUnsupportedVersion) {
+		t.Fatalf("expected errUnsupportedVersion, got %v", err)
+	}
+}
+
+func TestNodeKeyNamespace(t *testing.T) {
+	key := testPublicKey(1)
+
+	def, err := NodeKey(key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	explicit, err := NodeKey(key, map[string]interface{}{"namespace": DEFAULT_NAMESPACE})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(def, explicit) {
+		t.Fatal("a nil namespace should use DEFAULT_NAMESPACE")
+	}
+
+	custom, err := NodeKey(key, map[string]interface{}{"namespace": fill(0xaa)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Equal(def, custom) {
+		t.Fatal("the namespace does not change the key")
+	}
+
+	want, err := PublicKeyNodeKey(key, NodeKeyOptions{Namespace: fill(0xaa)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(custom, want) {
+		t.Fatalf("NodeKey = %x, PublicKeyNodeKey = %x", custom, want)
+	}
+
+	if _, err := NodeKey(key, map[string]interface{}{"namespace": []byte{1}}); !errors.Is(err, errInvalidSigner) {
+		t.Fatalf("expected errInvalidSigner, got %v", err)
+	}
+}
+
+func TestCreateManifest(t *testing.T) {
+	if _, err := createManifest(nil); !errors.Is(err, errNilManifest) {
+		t.Fatalf("expected errNilManifest, got %v", err)
+	}
+
+	signers := []map[string]interface{}{
+		{"publicKey": testPublicKey(1)},
+		{"publicKey": testPublicKey(2)},
+		{"publicKey": testPublicKey(3)},
+	}
+
+	tests := []struct {
+		name    string
+		inp     map[string]interface{}
+		version int
+		quorum  int
+	}{
+		{"defaults", map[string]interface{}{"signers": signers}, 1, 2},
+		{"explicit version 0", map[string]interface{}{"version": 0, "signers": signers}, 0, 2},
+		{"explicit quorum", map[string]interface{}{"quorum": 3, "signers": signers}, 1, 3},
+		{"explicit quorum 0", map[string]interface{}{"quorum": 0, "signers": signers}, 1, 0},
+		{"no signers", map[string]interface{}{}, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := createManifest(tt.inp)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if m.Version != tt.version || m.Quorum != tt.quorum {
+				t.Fatalf("got version %d quorum %d, want version %d quorum %d", m.Version, m.Quorum, tt.version, tt.quorum)
+			}
+		})
+	}
+}
+
+func TestManifestOptionsMatchMap(t *testing.T) {
+	key := testPublicKey(1)
+
+	fromOptions, err := ManifestKey(ManifestOptions{Signers: []SignerSpec{{PublicKey: key}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fromMap, err := NodeKey(map[string]interface{}{
+		"signers": []map[string]interface{}{{"publicKey": key}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(fromOptions, fromMap) {
+		t.Fatalf("ManifestOptions key %x differs from the map key %x", fromOptions, fromMap)
+	}
+}
+
+func TestCreateManifestErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		inp  map[string]interface{}
+		want error
+	}{
+		{"version type", map[string]interface{}{"version": "1"}, errInvalidOption},
+		{"quorum type", map[string]interface{}{"quorum": 1.5}, errInvalidOption},
+		{"quorum above signers", map[string]interface{}{"quorum": 1}, errInvalidQuorum},
+		{"signer type", map[string]interface{}{"signers": []interface{}{"key"}}, errInvalidSigner},
+		{"missing public key", map[string]interface{}{"signers": []map[string]interface{}{{}}}, errMissingPublicKey},
+		{"unsupported hash", map[string]interface{}{"hash": "sha256"}, errUnsupportedHash},
+		{"prologue without length", map[string]interface{}{"prologue": map[string]interface{}{"hash": fill(1)}}, errInvalidPrologue},
+		{"v0 prologue with signers", map[string]interface{}{
+			"version":  0,
+			"signers":  []map[string]interface{}{{"publicKey": testPublicKey(1)}},
+			"prologue": map[string]interface{}{"hash": fill(1), "length": 1},
+		}, errInvalidPrologue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := createManifest(tt.inp); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	errInvalidFixed32       = errors.New("invalid fixed length field")
+	errUnsupportedHash      = errors.New("only blake2b hashes are supported")
+	errUnsupportedSignature = errors.New("only ed25519 signatures are supported")
+	errUnsupportedVersion   = errors.New("unsupported manifest version")
+	errNoSigners            = errors.New("manifest has no signers")
+	errInvalidQuorum        = errors.New("invalid manifest quorum")
+)
+
+const (
+	manifestFlagAllowPatch = 1 << 0
+	manifestFlagPrologue   = 1 << 1
+)
+
+// v0 manifests record their shape as a type: a bare prologue, a single signer, or flags, quorum and signers.
+const (
+	manifestV0TypePrologue = 0
+	manifestV0TypeSigner   = 1
+	manifestV0TypeMultisig = 2
+)
+
+// encodeManifest encodes m the way Hypercore does before hashing it into a core key. Version 1 writes flags, hash, quorum, every signer and the optional prologue. Version 0 writes only the prologue hash for unsigned cores, a single signer on its own when it alone signs without patches, and flags, quorum and signers otherwise.
+func encodeManifest(m *manifest) ([]byte, error) {
+	if m.Quorum < 0 || m.Quorum > len(m.Signers) {
+		return nil, fmt.Errorf("%w: %d of %d signers", errInvalidQuorum, m.Quorum, len(m.Signers))
+	}
+
+	e := &encoder{}
+	e.uint(uint64(m.Version))
+
+	switch m.Version {
+	case 0:
+		if err := encodeManifestV0(e, m); err != nil {
+			return nil, err
+		}
+	case 1:
+		if err := encodeManifestV1(e, m); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %d", errUnsupportedVersion, m.Version)
+	}
+
+	return e.bytes(), nil
+}
+
+func encodeManifestV0(e *encoder, m *manifest) error {
+	if m.Prologue != nil && len(m.Signers) > 0 {
+		return fmt.Errorf("%w: version 0 manifests can only have a prologue without signers", errUnsupportedVersion)
+	}
+
+	if err := encodeHashType(e, m.Hash); err != nil {
+		return err
+	}
+
+	if m.Prologue != nil {
+		e.uint(manifestV0TypePrologue)
+
+		if err := e.fixed32(m.Prologue.Hash); err != nil {
+			return fmt.Errorf("prologue hash: %w", err)
+		}
+
+		return nil
+	}
+
+	if m.Quorum == 1 && len(m.Signers) == 1 && !m.AllowPatch {
+		e.uint(manifestV0TypeSigner)
+		return encodeSigner(e, m.Signers[0])
+	}
+
+	e.uint(manifestV0TypeMultisig)
+
+	flags := uint64(0)
+	if m.AllowPatch {
+		flags |= manifestFlagAllowPatch
+	}
+
+	e.uint(flags)
+	e.uint(uint64(m.Quorum))
+
+	return encodeSigners(e, m.Signers)
+}
+
+func encodeManifestV1(e *encoder, m *manifest) error {
+	flags := uint64(0)
+	if m.AllowPatch {
+		flags |= manifestFlagAllowPatch
+	}
+	if m.Prologue != nil {
+		flags |= manifestFlagPrologue
+	}
+
+	e.uint(flags)
+
+	if err := encodeHashType(e, m.Hash); err != nil {
+		return err
+	}
+
+	e.uint(uint64(m.Quorum))
+
+	if err := encodeSigners(e, m.Signers); err != nil {
+		return err
+	}
+
+	if m.Prologue == nil {
+		return nil
+	}
+
+	if m.Prologue.Length < 0 {
+		return errors.New("invalid prologue length")
+	}
+
+	if err := e.fixed32(m.Prologue.Hash); err != nil {
+		return fmt.Errorf("prologue hash: %w", err)
+	}
+
+	e.uint(uint64(m.Prologue.Length))
+
+	return nil
+}
+
+func encodeHashType(e *encoder, hash string) error {
+	if hash != "" && hash != "blake2b" {
+		return errUnsupportedHash
+	}
+
+	e.uint(0)
+
+	return nil
+}
+
+func encodeSigners(e *encoder, signers []signer) error {
+	e.uint(uint64(len(signers)))
+
+	for _, s := range signers {
+		if err := encodeSigner(e, s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func encodeSigner(e *encoder, s signer) error {
+	if s.Signature != "" && s.Signature != "ed25519" {
+		return errUnsupportedSignature
+	}
+
+	e.uint(0)
+
+	if err := e.fixed32(s.Namespace); err != nil {
+		return fmt.Errorf("signer namespace: %w", err)
+	}
+
+	if err := e.fixed32(s.PublicKey); err != nil {
+		return fmt.Errorf("signer public key: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package sync
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func fill(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func join(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func testSigner(key byte) signer {
+	return signer{Signature: "ed25519", Namespace: fill(0xaa), PublicKey: fill(key)}
+}
+
+// encodedSigner is a signer as Hypercore writes it: signature type 0, namespace, public key.
+func encodedSigner(key byte) []byte {
+	return join([]byte{0}, fill(0xaa), fill(key))
+}
+
+func TestEncodeUint(t *testing.T) {
+	tests := []struct {
+		n    uint64
+		want string
+	}{
+		{0, "00"},
+		{0xfc, "fc"},
+		{0xfd, "fdfd00"},
+		{0xffff, "fdffff"},
+		{0x10000, "fe00000100"},
+		{0xffffffff, "feffffffff"},
+		{0x100000000, "ff0000000001000000"},
+	}
+
+	for _, tt := range tests {
+		e := &encoder{}
+		e.uint(tt.n)
+
+		if got := hex.EncodeToString(e.bytes()); got != tt.want {
+			t.Errorf("uint(%d) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeManifest(t *testing.T) {
+	prologue := &Prologue{Hash: fill(0xcc), Length: 300}
+
+	tests := []struct {
+		name string
+		m    *manifest
+		want []byte
+	}{
+		{
+			name: "v0 prologue",
+			m:    &manifest{Version: 0, Prologue: prologue},
+			// version, hash, type 0, prologue hash; v0 does not record the length
+			want: join([]byte{0, 0, 0}, fill(0xcc)),
+		},
+		{
+			name: "v0 single signer",
+			m:    &manifest{Version: 0, Quorum: 1, Signers: []signer{testSigner(1)}},
+			want: join([]byte{0, 0, 1}, encodedSigner(1)),
+		},
+		{
+			name: "v0 single signer with patches",
+			m:    &manifest{Version: 0, Quorum: 1, AllowPatch: true, Signers: []signer{testSigner(1)}},
+			// version, hash, type 2, flags, quorum, signer count
+			want: join([]byte{0, 0, 2, 1, 1, 1}, encodedSigner(1)),
+		},
+		{
+			name: "v0 single signer without quorum",
+			m:    &manifest{Version: 0, Quorum: 0, Signers: []signer{testSigner(1)}},
+			want: join([]byte{0, 0, 2, 0, 0, 1}, encodedSigner(1)),
+		},
+		{
+			name: "v0 multisig",
+			m:    &manifest{Version: 0, Quorum: 2, Signers: []signer{testSigner(1), testSigner(2), testSigner(3)}},
+			want: join([]byte{0, 0, 2, 0, 2, 3}, encodedSigner(1), encodedSigner(2), encodedSigner(3)),
+		},
+		{
+			name: "v1 single signer",
+			m:    &manifest{Version: 1, Quorum: 1, Signers: []signer{testSigner(1)}},
+			// version, flags, hash, quorum, signer count
+			want: join([]byte{1, 0, 0, 1, 1}, encodedSigner(1)),
+		},
+		{
+			name: "v1 prologue",
+			m:    &manifest{Version: 1, Quorum: 1, AllowPatch: true, Signers: []signer{testSigner(1)}, Prologue: prologue},
+			// the length 300 is written as a 0xfd prefixed uint16
+			want: join([]byte{1, 3, 0, 1, 1}, encodedSigner(1), fill(0xcc), []byte{0xfd, 0x2c, 0x01}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeManifest(tt.m)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, tt.want) {
+				t.Fatalf("encodeManifest = %x\nwant %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeManifestErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		m    *manifest
+		want error
+	}{
+		{"v0 prologue with signers", &manifest{Version: 0, Quorum: 1, Signers: []signer{testSigner(1)}, Prologue: &Prologue{Hash: fill(0xcc)}}, errUnsupportedVersion},
+		{"unknown version", &manifest{Version: 2}, errUnsupportedVersion},
+		{"quorum above signers", &manifest{Version: 1, Quorum: 2, Signers: []signer{testSigner(1)}}, errInvalidQuorum},
+		{"short public key", &manifest{Version: 1, Quorum: 1, Signers: []signer{{Namespace: fill(0xaa), PublicKey: []byte{1}}}}, errInvalidFixed32},
+		{"unsupported hash", &manifest{Version: 1, Hash: "sha256"}, errUnsupportedHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := encodeManifest(tt.m); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
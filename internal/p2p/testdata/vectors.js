@@ -0,0 +1,67 @@
+// Generates vectors.json, the core keys the JavaScript hypercore and autobase derive for a fixed set of manifests.
+// The Go tests in this package load it to check that key derivation matches.
+//
+//   npm install hypercore hypercore-crypto autobase corestore random-access-memory b4a
+//   node vectors.js > vectors.json
+
+const Hypercore = require('hypercore')
+const Autobase = require('autobase')
+const Corestore = require('corestore')
+const RAM = require('random-access-memory')
+const crypto = require('hypercore-crypto')
+const b4a = require('b4a')
+
+const hex = (buf) => b4a.toString(buf, 'hex')
+
+function signer (seed) {
+  return {
+    signature: 'ed25519',
+    namespace: crypto.hash(b4a.from('namespace ' + seed)),
+    publicKey: crypto.keyPair(b4a.alloc(32, seed)).publicKey
+  }
+}
+
+function encode (manifest) {
+  return {
+    version: manifest.version,
+    quorum: manifest.quorum,
+    allowPatch: !!manifest.allowPatch,
+    signers: manifest.signers.map((s) => ({ namespace: hex(s.namespace), publicKey: hex(s.publicKey) })),
+    prologue: manifest.prologue ? { hash: hex(manifest.prologue.hash), length: manifest.prologue.length } : null
+  }
+}
+
+async function main () {
+  const manifests = [
+    { name: 'v0 single signer', manifest: { version: 0, quorum: 1, signers: [signer(1)] } },
+    { name: 'v1 single signer', manifest: { version: 1, quorum: 1, signers: [signer(1)] } },
+    { name: 'v1 multi signer', manifest: { version: 1, quorum: 2, allowPatch: true, signers: [signer(1), signer(2), signer(3)] } },
+    { name: 'v1 prologue', manifest: { version: 1, quorum: 1, signers: [signer(1)], prologue: { hash: crypto.hash(b4a.from('prologue')), length: 300 } } }
+  ]
+
+  const base = new Autobase(new Corestore(RAM.reusable()), null, {
+    open: (store) => store.get('autobee'),
+    apply: async () => {}
+  })
+  await base.ready()
+
+  const vectors = {
+    hypercore: require('hypercore/package.json').version,
+    autobase: require('autobase/package.json').version,
+    manifests: manifests.map(({ name, manifest }) => ({
+      name,
+      manifest: encode(manifest),
+      key: hex(Hypercore.key(manifest))
+    })),
+    autobaseKey: {
+      bootstrap: hex(base.local.manifest.signers[0].publicKey),
+      key: hex(base.view.key)
+    }
+  }
+
+  await base.close()
+
+  console.log(JSON.stringify(vectors, null, 2))
+}
+
+main()
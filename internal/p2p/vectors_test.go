@@ -0,0 +1,111 @@
+package sync
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+// jsVectors are core keys derived by the JavaScript hypercore and autobase, written by testdata/vectors.js.
+type jsVectors struct {
+	Hypercore string `json:"hypercore"`
+	Autobase  string `json:"autobase"`
+	Manifests []struct {
+		Name     string `json:"name"`
+		Manifest struct {
+			Version    int  `json:"version"`
+			Quorum     int  `json:"quorum"`
+			AllowPatch bool `json:"allowPatch"`
+			Signers    []struct {
+				Namespace string `json:"namespace"`
+				PublicKey string `json:"publicKey"`
+			} `json:"signers"`
+			Prologue *struct {
+				Hash   string `json:"hash"`
+				Length int    `json:"length"`
+			} `json:"prologue"`
+		} `json:"manifest"`
+		Key string `json:"key"`
+	} `json:"manifests"`
+	AutobaseKey struct {
+		Bootstrap string `json:"bootstrap"`
+		Key       string `json:"key"`
+	} `json:"autobaseKey"`
+}
+
+func loadJSVectors(t *testing.T) *jsVectors {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/vectors.json")
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Skip("testdata/vectors.json is missing, generate it with testdata/vectors.js")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var vectors jsVectors
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatal(err)
+	}
+
+	return &vectors
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func TestManifestKeyMatchesHypercore(t *testing.T) {
+	vectors := loadJSVectors(t)
+
+	for _, v := range vectors.Manifests {
+		t.Run(v.Name, func(t *testing.T) {
+			opts := ManifestOptions{
+				Version:    &v.Manifest.Version,
+				Quorum:     &v.Manifest.Quorum,
+				AllowPatch: v.Manifest.AllowPatch,
+			}
+
+			for _, s := range v.Manifest.Signers {
+				opts.Signers = append(opts.Signers, SignerSpec{Namespace: mustHex(t, s.Namespace), PublicKey: mustHex(t, s.PublicKey)})
+			}
+
+			if p := v.Manifest.Prologue; p != nil {
+				opts.Prologue = &Prologue{Hash: mustHex(t, p.Hash), Length: p.Length}
+			}
+
+			key, err := ManifestKey(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := hex.EncodeToString(key); got != v.Key {
+				t.Fatalf("key = %s, hypercore %s derives %s", got, vectors.Hypercore, v.Key)
+			}
+		})
+	}
+}
+
+func TestAutoBaseKeyMatchesAutobase(t *testing.T) {
+	vectors := loadJSVectors(t)
+
+	key, err := AutoBaseKey(mustHex(t, vectors.AutobaseKey.Bootstrap))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := hex.EncodeToString(key); got != vectors.AutobaseKey.Key {
+		t.Fatalf("key = %s, autobase %s derives %s", got, vectors.Autobase, vectors.AutobaseKey.Key)
+	}
+}
@@ -0,0 +1,194 @@
+package sync
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/samber/lo"
+)
+
+var (
+	LEAF_TYPE   = []byte{0}
+	PARENT_TYPE = []byte{1}
+	ROOT_TYPE   = []byte{2}
+)
+
+var (
+	ErrInvalidProof     = errors.New("invalid merkle proof")
+	ErrInvalidSignature = errors.New("invalid tree signature")
+	ErrQuorumNotReached = errors.New("not enough valid signatures to reach quorum")
+)
+
+// TreeNode is a node of a Hypercore style merkle tree, addressed by its flat tree index. Size is the number of block bytes beneath it.
+type TreeNode struct {
+	Index uint64
+	Size  uint64
+	Hash  []byte
+}
+
+// Signature is a signature over a core's tree by the signer at index Signer in the core's manifest.
+type Signature struct {
+	Signer    int
+	Signature []byte
+}
+
+// BlockProof proves that Value is block Index of a core that was Length blocks long at Fork. Nodes are the sibling of the block's leaf and of each ancestor up to the root it falls under, in that order. Roots are every full root of the tree at Length, left to right, and Signatures sign the tree they make up.
+type BlockProof struct {
+	Index      uint64
+	Value      []byte
+	Nodes      []TreeNode
+	Roots      []TreeNode
+	Length     uint64
+	Fork       uint64
+	Signatures []Signature
+}
+
+// VerifyBlock checks that the proof's block belongs to the tree it describes, and that the tree is signed by a quorum of the signers in opts.
+func VerifyBlock(opts ManifestOptions, proof *BlockProof) error {
+	if proof.Index >= proof.Length {
+		return fmt.Errorf("%w: block %d is past the tree length %d", ErrInvalidProof, proof.Index, proof.Length)
+	}
+
+	expected := FlatFullRoots(proof.Length)
+	if len(proof.Roots) != len(expected) {
+		return fmt.Errorf("%w: expected %d roots, got %d", ErrInvalidProof, len(expected), len(proof.Roots))
+	}
+
+	for i, root := range proof.Roots {
+		if root.Index != expected[i] || len(root.Hash) != 32 {
+			return fmt.Errorf("%w: unexpected root %d", ErrInvalidProof, root.Index)
+		}
+	}
+
+	node := LeafNode(proof.Index, proof.Value)
+
+	for _, sibling := range proof.Nodes {
+		if sibling.Index != FlatSibling(node.Index) || len(sibling.Hash) != 32 {
+			return fmt.Errorf("%w: node %d is not the sibling of %d", ErrInvalidProof, sibling.Index, node.Index)
+		}
+
+		node = ParentNode(node, sibling)
+	}
+
+	rooted := false
+	for _, root := range proof.Roots {
+		if root.Index == node.Index {
+			rooted = root.Size == node.Size && bytes.Equal(root.Hash, node.Hash)
+			break
+		}
+	}
+
+	if !rooted {
+		return fmt.Errorf("%w: block does not hash to a root of the tree", ErrInvalidProof)
+	}
+
+	return VerifyTreeSignature(opts, proof.Roots, proof.Length, proof.Fork, proof.Signatures)
+}
+
+// VerifyTreeSignature checks that at least a quorum of the manifest's signers signed the tree made up of roots. Each signer signs with its own namespace, so signatures cannot be replayed between cores.
+func VerifyTreeSignature(opts ManifestOptions, roots []TreeNode, length uint64, fork uint64, signatures []Signature) error {
+	m, err := newManifest(opts)
+	if err != nil {
+		return err
+	}
+
+	if len(m.Signers) == 0 {
+		return errNoSigners
+	}
+
+	tree := treeHash(roots)
+	valid := make(map[int]struct{}, len(signatures))
+
+	for _, sig := range signatures {
+		if sig.Signer < 0 || sig.Signer >= len(m.Signers) {
+			return fmt.Errorf("%w: unknown signer %d", ErrInvalidSignature, sig.Signer)
+		}
+
+		s := m.Signers[sig.Signer]
+		if ed25519.Verify(s.PublicKey, treeSignable(s.Namespace, tree, length, fork), sig.Signature) {
+			valid[sig.Signer] = struct{}{}
+		}
+	}
+
+	if len(valid) == 0 {
+		return ErrInvalidSignature
+	}
+
+	if len(valid) < m.Quorum {
+		return fmt.Errorf("%w: %d of %d", ErrQuorumNotReached, len(valid), m.Quorum)
+	}
+
+	return nil
+}
+
+// WriterManifest describes the core of an autobase writer, signed by the writer's key alone. It matches the manifest NodeKey derives for a bare public key.
+func WriterManifest(writer ed25519.PublicKey) ManifestOptions {
+	return ManifestOptions{
+		Version: lo.ToPtr(0),
+		Signers: []SignerSpec{{PublicKey: writer}},
+	}
+}
+
+// SignTree signs the tree made up of roots as the manifest signer with the given namespace does.
+func SignTree(key ed25519.PrivateKey, namespace []byte, roots []TreeNode, length uint64, fork uint64) []byte {
+	return ed25519.Sign(key, treeSignable(namespace, treeHash(roots), length, fork))
+}
+
+// LeafNode returns the tree node of block index, which holds data.
+func LeafNode(index uint64, data []byte) TreeNode {
+	return TreeNode{
+		Index: 2 * index,
+		Size:  uint64(len(data)),
+		Hash:  leafHash(data),
+	}
+}
+
+// ParentNode returns the node above the siblings a and b.
+func ParentNode(a TreeNode, b TreeNode) TreeNode {
+	return TreeNode{
+		Index: FlatParent(a.Index),
+		Size:  a.Size + b.Size,
+		Hash:  parentHash(a, b),
+	}
+}
+
+func leafHash(data []byte) []byte {
+	return hash([][]byte{LEAF_TYPE, uint64LE(uint64(len(data))), data}, nil)
+}
+
+func parentHash(a TreeNode, b TreeNode) []byte {
+	if a.Index > b.Index {
+		a, b = b, a
+	}
+
+	return hash([][]byte{PARENT_TYPE, uint64LE(a.Size + b.Size), a.Hash, b.Hash}, nil)
+}
+
+func treeHash(roots []TreeNode) []byte {
+	buf := make([][]byte, 0, 1+3*len(roots))
+	buf = append(buf, ROOT_TYPE)
+
+	for _, root := range roots {
+		buf = append(buf, root.Hash, uint64LE(root.Index), uint64LE(root.Size))
+	}
+
+	return hash(buf, nil)
+}
+
+// treeSignable is what a signer signs for a tree: the TREE namespace, the signer's namespace, the tree hash, length and fork.
+func treeSignable(namespace []byte, tree []byte, length uint64, fork uint64) []byte {
+	buf := make([]byte, 0, 112)
+	buf = append(buf, TREE...)
+	buf = append(buf, namespace...)
+	buf = append(buf, tree...)
+	buf = append(buf, uint64LE(length)...)
+	buf = append(buf, uint64LE(fork)...)
+
+	return buf
+}
+
+func uint64LE(n uint64) []byte {
+	return binary.LittleEndian.AppendUint64(nil, n)
+}
@@ -0,0 +1,214 @@
+package sync
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/samber/lo"
+	"testing"
+)
+
+// Expected hashes follow the leaf, parent and tree hash construction of hypercore-crypto. They were computed
+// independently with Python's hashlib, not by the JavaScript library.
+
+func TestLeafHash(t *testing.T) {
+	want := "9f1b578fd57a4df015493d2886aec9600eef913c3bb009768c7f0fb875996308"
+
+	if got := hex.EncodeToString(leafHash([]byte("hello world"))); got != want {
+		t.Fatalf("leafHash = %s, want %s", got, want)
+	}
+}
+
+func TestParentHash(t *testing.T) {
+	want := "3ad0c9b58b771d1b7707e1430f37c23a23dd46e0c7c3ab9c16f79d25f7c36804"
+	leaf := leafHash([]byte("hello world"))
+
+	a := TreeNode{Index: 0, Size: 11, Hash: leaf}
+	b := TreeNode{Index: 2, Size: 11, Hash: leaf}
+
+	if got := hex.EncodeToString(parentHash(a, b)); got != want {
+		t.Fatalf("parentHash = %s, want %s", got, want)
+	}
+
+	if got := hex.EncodeToString(parentHash(b, a)); got != want {
+		t.Fatalf("parentHash is not order independent: %s", got)
+	}
+}
+
+func TestTreeHash(t *testing.T) {
+	want := "0e576a56b478cddb6ffebab8c494532b6de009466b2e9f7af9143fc54b9eaa36"
+	roots := []TreeNode{
+		{Index: 3, Size: 11, Hash: make([]byte, 32)},
+		{Index: 9, Size: 2, Hash: make([]byte, 32)},
+	}
+
+	if got := hex.EncodeToString(treeHash(roots)); got != want {
+		t.Fatalf("treeHash = %s, want %s", got, want)
+	}
+}
+
+// testTree builds a core of blocks and returns its nodes by flat index.
+func testTree(blocks [][]byte) map[uint64]TreeNode {
+	nodes := make(map[uint64]TreeNode)
+
+	for i, block := range blocks {
+		node := LeafNode(uint64(i), block)
+		nodes[node.Index] = node
+
+		for FlatSibling(node.Index) < node.Index {
+			node = ParentNode(nodes[FlatSibling(node.Index)], node)
+			nodes[node.Index] = node
+		}
+	}
+
+	return nodes
+}
+
+func testProof(blocks [][]byte, index uint64, keys ...ed25519.PrivateKey) (*BlockProof, ManifestOptions) {
+	nodes := testTree(blocks)
+	length := uint64(len(blocks))
+
+	proof := &BlockProof{Index: index, Value: blocks[index], Length: length}
+
+	rooted := make(map[uint64]bool)
+	for _, root := range FlatFullRoots(length) {
+		proof.Roots = append(proof.Roots, nodes[root])
+		rooted[root] = true
+	}
+
+	for i := 2 * index; !rooted[i]; i = FlatParent(i) {
+		proof.Nodes = append(proof.Nodes, nodes[FlatSibling(i)])
+	}
+
+	var opts ManifestOptions
+	for i, key := range keys {
+		opts.Signers = append(opts.Signers, SignerSpec{PublicKey: key.Public().(ed25519.PublicKey)})
+		proof.Signatures = append(proof.Signatures, Signature{
+			Signer:    i,
+			Signature: SignTree(key, DEFAULT_NAMESPACE, proof.Roots, length, 0),
+		})
+	}
+
+	return proof, opts
+}
+
+func testKeys(t *testing.T, n int) []ed25519.PrivateKey {
+	t.Helper()
+
+	keys := make([]ed25519.PrivateKey, n)
+	for i := range keys {
+		_, key, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = key
+	}
+
+	return keys
+}
+
+func testBlocks(n int) [][]byte {
+	blocks := make([][]byte, n)
+	for i := range blocks {
+		blocks[i] = []byte(fmt.Sprintf("block %d", i))
+	}
+
+	return blocks
+}
+
+func TestVerifyBlock(t *testing.T) {
+	keys := testKeys(t, 1)
+
+	for _, length := range []int{1, 2, 3, 5, 8, 11} {
+		blocks := testBlocks(length)
+
+		for index := range blocks {
+			proof, opts := testProof(blocks, uint64(index), keys...)
+
+			if err := VerifyBlock(opts, proof); err != nil {
+				t.Fatalf("block %d of %d: %v", index, length, err)
+			}
+		}
+	}
+
+	writer := keys[0].Public().(ed25519.PublicKey)
+	proof, _ := testProof(testBlocks(5), 2, keys...)
+
+	if err := VerifyBlock(WriterManifest(writer), proof); err != nil {
+		t.Fatalf("writer manifest: %v", err)
+	}
+}
+
+func TestVerifyBlockRejects(t *testing.T) {
+	keys := testKeys(t, 2)
+	blocks := testBlocks(5)
+
+	tests := []struct {
+		name   string
+		mutate func(*BlockProof, *ManifestOptions)
+		want   error
+	}{
+		{"tampered value", func(p *BlockProof, _ *ManifestOptions) { p.Value = []byte("tampered") }, ErrInvalidProof},
+		{"wrong index", func(p *BlockProof, _ *ManifestOptions) { p.Index = 3 }, ErrInvalidProof},
+		{"past length", func(p *BlockProof, _ *ManifestOptions) { p.Index = 5 }, ErrInvalidProof},
+		{"missing root", func(p *BlockProof, _ *ManifestOptions) { p.Roots = p.Roots[:1] }, ErrInvalidProof},
+		{"tampered sibling", func(p *BlockProof, _ *ManifestOptions) { p.Nodes[0].Hash = make([]byte, 32) }, ErrInvalidProof},
+		{"other length", func(p *BlockProof, _ *ManifestOptions) { p.Length = 6 }, ErrInvalidProof},
+		{"other fork", func(p *BlockProof, _ *ManifestOptions) { p.Fork = 1 }, ErrInvalidSignature},
+		{"other signer", func(_ *BlockProof, o *ManifestOptions) {
+			o.Signers[0].PublicKey = keys[1].Public().(ed25519.PublicKey)
+		}, ErrInvalidSignature},
+		{"other namespace", func(_ *BlockProof, o *ManifestOptions) { o.Signers[0].Namespace = fill(0xaa) }, ErrInvalidSignature},
+		{"unknown signer", func(p *BlockProof, _ *ManifestOptions) { p.Signatures[0].Signer = 1 }, ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proof, opts := testProof(blocks, 2, keys[0])
+			tt.mutate(proof, &opts)
+
+			if err := VerifyBlock(opts, proof); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestVerifyTreeSignatureQuorum(t *testing.T) {
+	keys := testKeys(t, 3)
+	proof, opts := testProof(testBlocks(3), 0, keys...)
+
+	if err := VerifyTreeSignature(opts, proof.Roots, proof.Length, proof.Fork, proof.Signatures); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyTreeSignature(opts, proof.Roots, proof.Length, proof.Fork, proof.Signatures[1:]); err != nil {
+		t.Fatalf("a majority should reach quorum: %v", err)
+	}
+
+	if err := VerifyTreeSignature(opts, proof.Roots, proof.Length, proof.Fork, proof.Signatures[:1]); !errors.Is(err, ErrQuorumNotReached) {
+		t.Fatalf("expected ErrQuorumNotReached, got %v", err)
+	}
+
+	duplicated := []Signature{proof.Signatures[0], proof.Signatures[0]}
+	if err := VerifyTreeSignature(opts, proof.Roots, proof.Length, proof.Fork, duplicated); !errors.Is(err, ErrQuorumNotReached) {
+		t.Fatalf("a repeated signature should count once, got %v", err)
+	}
+
+	opts.Quorum = lo.ToPtr(3)
+	if err := VerifyTreeSignature(opts, proof.Roots, proof.Length, proof.Fork, proof.Signatures[1:]); !errors.Is(err, ErrQuorumNotReached) {
+		t.Fatalf("expected ErrQuorumNotReached with an explicit quorum, got %v", err)
+	}
+}
+
+func TestTreeSignable(t *testing.T) {
+	tree := fill(0xbb)
+	signable := treeSignable(DEFAULT_NAMESPACE, tree, 3, 1)
+
+	want := join(TREE, DEFAULT_NAMESPACE, tree, []byte{3, 0, 0, 0, 0, 0, 0, 0}, []byte{1, 0, 0, 0, 0, 0, 0, 0})
+	if !bytes.Equal(signable, want) {
+		t.Fatalf("treeSignable = %x, want %x", signable, want)
+	}
+}
@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		perSecond float64
+		want      time.Duration
+	}{
+		{0, 0},
+		{-1, 0},
+		{1, time.Second},
+		{4, 250 * time.Millisecond},
+		{0.5, 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.perSecond)
+
+		if tt.want == 0 {
+			if l != nil {
+				t.Errorf("New(%v) = %+v, want nil", tt.perSecond, l)
+			}
+			continue
+		}
+
+		if l == nil || l.interval != tt.want {
+			t.Errorf("New(%v) interval = %+v, want %v", tt.perSecond, l, tt.want)
+		}
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	var l *Limiter
+
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := l.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestBurst(t *testing.T) {
+	l := New(20)
+
+	// An idle limiter lets one call through at once but does not bank unused time.
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 2*l.interval {
+		t.Fatalf("3 calls took %v, want at least %v", elapsed, 2*l.interval)
+	}
+}
+
+func TestRefill(t *testing.T) {
+	l := New(20)
+
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(l.interval)
+
+	start := time.Now()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed > l.interval/2 {
+		t.Fatalf("call after a full interval waited %v", elapsed)
+	}
+}
+
+func TestWaitCancelled(t *testing.T) {
+	l := New(1)
+
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	const callers = 10
+	l := New(100)
+
+	var wg sync.WaitGroup
+	start := time.Now()
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.Wait(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The first caller goes straight through and every other one waits its own interval.
+	if elapsed := time.Since(start); elapsed < (callers-1)*l.interval {
+		t.Fatalf("%d concurrent calls took %v, want at least %v", callers, elapsed, (callers-1)*l.interval)
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+	"strings"
+)
+
+// Indexers
+//
+// The node that wins the first election writes its ID to ETC_SYNC_BOOTSTRAP_KEY. That key never changes: the log key
+// every node reports is derived from the bootstrap node, so rewriting it would fork the log.
+//
+// The nodes indexing the log are tracked separately as a set of node IDs under ETC_SYNC_INDEXER_PREFIX, and that set
+// is what the log view and its quorum are built from. Neither backend can add an indexer to a running log yet, so the
+// set is not changed automatically when an indexer leaves. Clusters created before the indexer set existed are
+// migrated on startup by seeding the set with the bootstrap node.
+
+const ETC_SYNC_INDEXER_PREFIX = "/sync/indexers/"
+
+// migrateIndexers seeds the indexer set with the bootstrap node for clusters created before it existed.
+func (s *SyncServiceDefault) migrateIndexers(ctx context.Context, client *clientv3.Client) error {
+	resp, err := client.Get(ctx, ETC_SYNC_INDEXER_PREFIX, clientv3.WithPrefix(), clientv3.WithCountOnly())
+	if err != nil {
+		return err
+	}
+
+	if resp.Count > 0 {
+		return nil
+	}
+
+	bootstrapID, err := s.bootstrapNodeID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if bootstrapID == "" {
+		return nil
+	}
+
+	key := ETC_SYNC_INDEXER_PREFIX + bootstrapID
+
+	_, err = client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, string(s.originKey))).
+		Commit()
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("migrated sync bootstrap node to indexer set", zap.String("node", bootstrapID))
+
+	return nil
+}
+
+func listIndexers(ctx context.Context, client *clientv3.Client) ([]string, error) {
+	resp, err := client.Get(ctx, ETC_SYNC_INDEXER_PREFIX, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return nil, err
+	}
+
+	indexers := make([]string, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		indexers = append(indexers, strings.TrimPrefix(string(kv.Key), ETC_SYNC_INDEXER_PREFIX))
+	}
+
+	return indexers, nil
+}
+
+// indexerKeys returns the public key of every indexer by node ID.
+func (s *SyncServiceDefault) indexerKeys(ctx context.Context, client *clientv3.Client) (map[string]ed25519.PublicKey, error) {
+	resp, err := client.Get(ctx, ETC_SYNC_INDEXER_PREFIX, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]ed25519.PublicKey, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		nodeID := strings.TrimPrefix(string(kv.Key), ETC_SYNC_INDEXER_PREFIX)
+		key := ed25519.PublicKey(kv.Value)
+
+		if len(key) == 0 {
+			s.logger.Warn("sync indexer has no public key", zap.String("node", nodeID))
+			continue
+		}
+
+		keys[nodeID] = key
+	}
+
+	return keys, nil
+}
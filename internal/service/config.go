@@ -1,17 +1,113 @@
 package service
 
 import (
+	"go.lumeweb.com/portal-plugin-sync/internal/cron/define"
 	"go.lumeweb.com/portal/config"
+	"time"
 )
 
 var _ config.ServiceConfig = (*ServiceConfig)(nil)
+var _ define.ScanObjectsConfigurer = (*ServiceConfig)(nil)
+
+const BACKEND_NODE = "node"
+const BACKEND_NATIVE = "native"
 
 type ServiceConfig struct {
 	Enabled bool `mapstructure:"enabled"`
+	// Backend is BACKEND_NODE or BACKEND_NATIVE. The native backend keeps its log on this node only and does not replicate, so it is refused when clustering is enabled; clustered portals must use the node backend.
+	Backend  string        `mapstructure:"backend"`
+	Timeouts TimeoutConfig `mapstructure:"timeouts"`
+	// KnownHosts lists host public keys, e.g. "ed25519:<hex>", that are preferred when picking an import candidate.
+	KnownHosts []string `mapstructure:"known_hosts"`
+	// StreamingImport verifies and uploads imported objects in a single download instead of separate verify and upload jobs.
+	StreamingImport bool                     `mapstructure:"streaming_import"`
+	Scan            define.ScanObjectsConfig `mapstructure:"scan"`
+	Retry           RetryConfig              `mapstructure:"retry"`
+	// Admins lists the user IDs allowed to use the admin API.
+	Admins []uint      `mapstructure:"admins"`
+	Quota  QuotaConfig `mapstructure:"quota"`
+	// MaxImportSize is the largest object, in bytes, that can be imported. Zero means no limit.
+	MaxImportSize uint64 `mapstructure:"max_import_size"`
+	// RequireProof makes imports reject log entries that cannot be proven to come from an authorized writer. Unset, it is on for the native backend only: the node backend does not return proofs yet, so with it every import is rejected.
+	RequireProof *bool `mapstructure:"require_proof"`
+	// Quorum is how many indexers must sign the log view for it to advance. Zero requires a majority; otherwise it must be between 1 and the number of indexers.
+	Quorum int `mapstructure:"quorum"`
+	// LeaseTTL is how long this node's etcd registration outlives it if it stops renewing without shutting down cleanly.
+	LeaseTTL time.Duration `mapstructure:"lease_ttl"`
+	// DenylistFile is a line-based list of hex hashes loaded into the denylist on startup.
+	DenylistFile string `mapstructure:"denylist_file"`
+}
+
+// RetryConfig controls how failed publishes are retried before they are moved to the dead letter list.
+type RetryConfig struct {
+	MaxAttempts int           `mapstructure:"max_attempts"`
+	BaseDelay   time.Duration `mapstructure:"base_delay"`
+	MaxDelay    time.Duration `mapstructure:"max_delay"`
+	BatchSize   int           `mapstructure:"batch_size"`
+}
+
+// QuotaConfig limits how much each user can import. A zero value disables that limit.
+type QuotaConfig struct {
+	MaxConcurrent int    `mapstructure:"max_concurrent"`
+	MaxPerDay     int    `mapstructure:"max_per_day"`
+	MaxBytes      uint64 `mapstructure:"max_bytes"`
+}
+
+// TimeoutConfig bounds each sidecar RPC. A zero value disables the timeout for that call.
+type TimeoutConfig struct {
+	Init   time.Duration `mapstructure:"init"`
+	Update time.Duration `mapstructure:"update"`
+	Query  time.Duration `mapstructure:"query"`
+	Nodes  time.Duration `mapstructure:"nodes"`
 }
 
 func (s ServiceConfig) Defaults() map[string]any {
 	return map[string]any{
-		"enabled": false,
+		"enabled":          false,
+		"backend":          BACKEND_NODE,
+		"known_hosts":      []string{},
+		"streaming_import": false,
+		"scan": map[string]any{
+			"workers":           4,
+			"metadata_rate":     0,
+			"proof_rate":        0,
+			"update_rate":       0,
+			"progress_interval": time.Minute,
+		},
+		"retry": map[string]any{
+			"max_attempts": 10,
+			"base_delay":   time.Minute,
+			"max_delay":    6 * time.Hour,
+			"batch_size":   100,
+		},
+		"admins":          []uint{},
+		"max_import_size": 0,
+		"quota": map[string]any{
+			"max_concurrent": 0,
+			"max_per_day":    0,
+			"max_bytes":      0,
+		},
+		"denylist_file": "",
+		"lease_ttl":     time.Minute,
+		"quorum":        0,
+		"timeouts": map[string]any{
+			"init":   time.Minute,
+			"update": 30 * time.Second,
+			"query":  30 * time.Second,
+			"nodes":  30 * time.Second,
+		},
 	}
 }
+
+func (s *ServiceConfig) ScanObjectsConfig() define.ScanObjectsConfig {
+	return s.Scan
+}
+
+// requireProof reports whether imports need a proof, defaulting by backend when RequireProof is unset.
+func (s *ServiceConfig) requireProof() bool {
+	if s.RequireProof != nil {
+		return *s.RequireProof
+	}
+
+	return s.Backend == BACKEND_NATIVE
+}
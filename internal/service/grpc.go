@@ -8,20 +8,23 @@ import (
 	"go.lumeweb.com/portal-plugin-sync-grpc/gen/proto"
 	"go.lumeweb.com/portal-plugin-sync/internal/metadata"
 	"google.golang.org/grpc"
+	"time"
 )
 
 var _ Sync = (*SyncGRPC)(nil)
 
 type Sync interface {
-	Init(logPublicKey ed25519.PublicKey, nodePrivateKey ed25519.PrivateKey, dataDir string) error
-	Update(meta metadata.FileMeta) error
-	Query(keys []string) ([]*metadata.FileMeta, error)
-	UpdateNodes(nodes []ed25519.PublicKey) error
-	RemoveNode(node ed25519.PublicKey) error
+	Init(ctx context.Context, logPublicKey ed25519.PublicKey, nodePrivateKey ed25519.PrivateKey, dataDir string) error
+	Update(ctx context.Context, meta metadata.FileMeta) error
+	Query(ctx context.Context, keys []string) ([]*metadata.FileMeta, error)
+	UpdateNodes(ctx context.Context, nodes []ed25519.PublicKey) error
+	RemoveNode(ctx context.Context, node ed25519.PublicKey) error
+	Remove(ctx context.Context, hash []byte, protocol string) error
 }
 
 type SyncGrpcPlugin struct {
 	plugin.Plugin
+	timeouts TimeoutConfig
 }
 
 func (p *SyncGrpcPlugin) GRPCServer(_ *plugin.GRPCBroker, _ *grpc.Server) error {
@@ -29,7 +32,7 @@ func (p *SyncGrpcPlugin) GRPCServer(_ *plugin.GRPCBroker, _ *grpc.Server) error
 }
 
 func (p *SyncGrpcPlugin) GRPCClient(_ context.Context, _ *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
-	return &SyncGRPC{client: proto.NewSyncClient(c)}, nil
+	return &SyncGRPC{client: proto.NewSyncClient(c), timeouts: p.timeouts}, nil
 }
 
 type Result struct {
@@ -38,11 +41,15 @@ type Result struct {
 	Length uint
 }
 type SyncGRPC struct {
-	client proto.SyncClient
+	client   proto.SyncClient
+	timeouts TimeoutConfig
 }
 
-func (b *SyncGRPC) Init(logPublicKey ed25519.PublicKey, nodePrivateKey ed25519.PrivateKey, dataDir string) error {
-	_, err := b.client.Init(context.Background(), &proto.InitRequest{LogPublicKey: logPublicKey, NodePrivateKey: nodePrivateKey, DataDir: dataDir})
+func (b *SyncGRPC) Init(ctx context.Context, logPublicKey ed25519.PublicKey, nodePrivateKey ed25519.PrivateKey, dataDir string) error {
+	ctx, cancel := withTimeout(ctx, b.timeouts.Init)
+	defer cancel()
+
+	_, err := b.client.Init(ctx, &proto.InitRequest{LogPublicKey: logPublicKey, NodePrivateKey: nodePrivateKey, DataDir: dataDir})
 
 	if err != nil {
 		return err
@@ -50,8 +57,11 @@ func (b *SyncGRPC) Init(logPublicKey ed25519.PublicKey, nodePrivateKey ed25519.P
 
 	return nil
 }
-func (b *SyncGRPC) Update(meta metadata.FileMeta) error {
-	_, err := b.client.Update(context.Background(), &proto.UpdateRequest{Data: meta.ToProtobuf()})
+func (b *SyncGRPC) Update(ctx context.Context, meta metadata.FileMeta) error {
+	ctx, cancel := withTimeout(ctx, b.timeouts.Update)
+	defer cancel()
+
+	_, err := b.client.Update(ctx, &proto.UpdateRequest{Data: meta.ToProtobuf()})
 
 	if err != nil {
 		return err
@@ -60,8 +70,11 @@ func (b *SyncGRPC) Update(meta metadata.FileMeta) error {
 	return nil
 }
 
-func (b *SyncGRPC) Query(keys []string) ([]*metadata.FileMeta, error) {
-	ret, err := b.client.Query(context.Background(), &proto.QueryRequest{Keys: keys})
+func (b *SyncGRPC) Query(ctx context.Context, keys []string) ([]*metadata.FileMeta, error) {
+	ctx, cancel := withTimeout(ctx, b.timeouts.Query)
+	defer cancel()
+
+	ret, err := b.client.Query(ctx, &proto.QueryRequest{Keys: keys})
 
 	if err != nil {
 		return nil, err
@@ -84,12 +97,15 @@ func (b *SyncGRPC) Query(keys []string) ([]*metadata.FileMeta, error) {
 	return meta, nil
 }
 
-func (b *SyncGRPC) UpdateNodes(nodes []ed25519.PublicKey) error {
+func (b *SyncGRPC) UpdateNodes(ctx context.Context, nodes []ed25519.PublicKey) error {
+	ctx, cancel := withTimeout(ctx, b.timeouts.Nodes)
+	defer cancel()
+
 	nodeList := lo.Map[ed25519.PublicKey, []byte](nodes, func(node ed25519.PublicKey, _ int) []byte {
 		return node
 	})
 
-	ret, err := b.client.UpdateNodes(context.Background(), &proto.UpdateNodesRequest{Nodes: nodeList})
+	ret, err := b.client.UpdateNodes(ctx, &proto.UpdateNodesRequest{Nodes: nodeList})
 
 	if err != nil {
 		return err
@@ -102,8 +118,11 @@ func (b *SyncGRPC) UpdateNodes(nodes []ed25519.PublicKey) error {
 	return nil
 }
 
-func (b *SyncGRPC) RemoveNode(node ed25519.PublicKey) error {
-	_, err := b.client.RemoveNode(context.Background(), &proto.RemoveNodeRequest{Node: node})
+func (b *SyncGRPC) RemoveNode(ctx context.Context, node ed25519.PublicKey) error {
+	ctx, cancel := withTimeout(ctx, b.timeouts.Nodes)
+	defer cancel()
+
+	_, err := b.client.RemoveNode(ctx, &proto.RemoveNodeRequest{Node: node})
 
 	if err != nil {
 		return err
@@ -111,3 +130,17 @@ func (b *SyncGRPC) RemoveNode(node ed25519.PublicKey) error {
 
 	return nil
 }
+
+// Remove writes a tombstone for hash. The sidecar has no dedicated call for it, so the tombstone is published as a regular update and nothing is deleted from the log.
+func (b *SyncGRPC) Remove(ctx context.Context, hash []byte, protocol string) error {
+	return b.Update(ctx, metadata.NewTombstone(hash, protocol))
+}
+
+// withTimeout bounds ctx by timeout. A zero timeout leaves ctx unbounded.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
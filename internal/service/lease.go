@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// leaseRevokeTimeout bounds revoking the node lease on shutdown, when the service context may already be cancelled.
+const leaseRevokeTimeout = 5 * time.Second
+
+// nodeLease is the etcd lease holding this node's sync registration.
+type nodeLease struct {
+	mu      sync.Mutex
+	id      clientv3.LeaseID
+	stopped bool
+}
+
+// registerNode writes the node's registration under a lease and keeps the lease alive for the lifetime of the service. If the lease is lost, for example after the etcd session drops, the node registers again.
+func (s *SyncServiceDefault) registerNode(ctx context.Context, client *clientv3.Client, key string, value string) error {
+	ttl := int64(s.getConfig().LeaseTTL.Seconds())
+	if ttl < 1 {
+		ttl = 1
+	}
+
+	grantResp, err := client.Grant(ctx, ttl)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Put(ctx, key, value, clientv3.WithLease(grantResp.ID))
+	if err != nil {
+		return err
+	}
+
+	keepAlive, err := client.KeepAlive(s.ctx, grantResp.ID)
+	if err != nil {
+		return err
+	}
+
+	s.lease.mu.Lock()
+	s.lease.id = grantResp.ID
+	s.lease.mu.Unlock()
+
+	go s.keepNodeAlive(client, key, value, keepAlive)
+
+	return nil
+}
+
+func (s *SyncServiceDefault) keepNodeAlive(client *clientv3.Client, key string, value string, keepAlive <-chan *clientv3.LeaseKeepAliveResponse) {
+	// The channel closes once the lease can no longer be renewed or the service stops.
+	for range keepAlive {
+	}
+
+	for {
+		if s.ctx.Err() != nil {
+			return
+		}
+
+		s.lease.mu.Lock()
+		stopped := s.lease.stopped
+		s.lease.mu.Unlock()
+
+		if stopped {
+			return
+		}
+
+		s.logger.Warn("sync node lease lost, registering again")
+
+		err := s.registerNode(s.ctx, client, key, value)
+		if err == nil {
+			return
+		}
+
+		s.logger.Error("failed to register sync node", zap.Error(err))
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(nodeResyncDelay):
+		}
+	}
+}
+
+// deregisterNode revokes the node lease so other nodes see this one leave immediately instead of after the lease expires.
+func (s *SyncServiceDefault) deregisterNode() {
+	s.lease.mu.Lock()
+	s.lease.stopped = true
+	id := s.lease.id
+	s.lease.mu.Unlock()
+
+	if id == clientv3.NoLease {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), leaseRevokeTimeout)
+	defer cancel()
+
+	_, err := s.etcd.Revoke(ctx, id)
+	if err != nil {
+		s.logger.Error("failed to revoke sync node lease", zap.Error(err))
+	}
+}
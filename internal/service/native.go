@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"go.lumeweb.com/portal-plugin-sync-grpc/gen/proto"
+	"go.lumeweb.com/portal-plugin-sync/internal/metadata"
+	"go.lumeweb.com/portal-plugin-sync/internal/synclog"
+	"go.lumeweb.com/portal/core"
+	protobuf "google.golang.org/protobuf/proto"
+	"io"
+)
+
+var _ Sync = (*SyncNative)(nil)
+var _ io.Closer = (*SyncNative)(nil)
+
+var ErrNativeClustered = errors.New("the native sync backend does not replicate and cannot be used when clustering is enabled")
+
+// SyncNative implements Sync in-process on top of a local synclog.Log, without the Node.js sidecar. The log is not replicated to other portals, so it only suits single node setups.
+type SyncNative struct {
+	log *synclog.Log
+}
+
+func NewSyncNative() *SyncNative {
+	return &SyncNative{}
+}
+
+func (n *SyncNative) Init(_ context.Context, logPublicKey ed25519.PublicKey, nodePrivateKey ed25519.PrivateKey, dataDir string) error {
+	log, err := synclog.Open(dataDir, logPublicKey, nodePrivateKey)
+	if err != nil {
+		return err
+	}
+
+	n.log = log
+
+	return nil
+}
+
+func (n *SyncNative) Update(ctx context.Context, meta metadata.FileMeta) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := protobuf.Marshal(meta.ToProtobuf())
+	if err != nil {
+		return err
+	}
+
+	_, err = n.log.Append(meta.Hash, data, fileMetaLookups(meta))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (n *SyncNative) Query(ctx context.Context, keys []string) ([]*metadata.FileMeta, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	entries, err := n.log.QueryEntries(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	meta := make([]*metadata.FileMeta, 0, len(entries))
+
+	for _, entry := range entries {
+		fileMeta, err := decodeFileMeta(entry.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		proof, err := n.log.Proof(entry)
+		if err != nil && !errors.Is(err, synclog.ErrNoProof) {
+			return nil, err
+		}
+
+		fileMeta.Writer = entry.Writer
+		fileMeta.LogProof = proof
+		meta = append(meta, fileMeta)
+	}
+
+	return meta, nil
+}
+
+func (n *SyncNative) UpdateNodes(_ context.Context, nodes []ed25519.PublicKey) error {
+	return n.log.SetWriters(nodes)
+}
+
+func (n *SyncNative) RemoveNode(_ context.Context, node ed25519.PublicKey) error {
+	return n.log.RemoveWriter(node)
+}
+
+func (n *SyncNative) Remove(ctx context.Context, hash []byte, protocol string) error {
+	return n.Update(ctx, metadata.NewTombstone(hash, protocol))
+}
+
+func (n *SyncNative) Close() error {
+	if n.log == nil {
+		return nil
+	}
+
+	return n.log.Close()
+}
+
+// decodeFileMeta decodes a FileMeta from its protobuf form, as stored in log entries.
+func decodeFileMeta(value []byte) (*metadata.FileMeta, error) {
+	var data proto.FileMeta
+	err := protobuf.Unmarshal(value, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata.FileMetaFromProtobuf(&data)
+}
+
+// fileMetaLookups returns every key a FileMeta can be queried by: the hex hash, the protocol identifier and any aliases.
+func fileMetaLookups(meta metadata.FileMeta) []string {
+	lookups := []string{hex.EncodeToString(meta.Hash)}
+
+	if syncProto, ok := core.GetProtocol(meta.Protocol).(SyncProtocol); ok {
+		lookups = append(lookups, syncProto.EncodeFileName(meta.Hash))
+	}
+
+	for _, alias := range meta.Aliases {
+		if alias != metadata.TOMBSTONE_ALIAS {
+			lookups = append(lookups, alias)
+		}
+	}
+
+	return lookups
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"github.com/samber/lo"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	sync "go.lumeweb.com/portal-plugin-sync/internal/p2p"
+	syncTypes "go.lumeweb.com/portal-plugin-sync/types"
+	"go.lumeweb.com/portal/config/types"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+var ErrInvalidQuorum = errors.New("sync quorum must be 0 for a majority, or between 1 and the number of indexers")
+
+// nodeResyncDelay is how long to wait before retrying a failed node resync.
+const nodeResyncDelay = 5 * time.Second
+
+// ListSyncNodes returns every node registered under the etcd sync prefix.
+func (s *SyncServiceDefault) ListSyncNodes(ctx context.Context) ([]syncTypes.SyncNodeInfo, error) {
+	if s.etcd == nil {
+		return nil, syncTypes.ErrClusterDisabled
+	}
+
+	resp, err := s.etcd.Get(ctx, ETC_NODE_PREFIX, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	bootstrapID, err := s.bootstrapNodeID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	indexers, err := listIndexers(ctx, s.etcd)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]syncTypes.SyncNodeInfo, 0, len(resp.Kvs))
+
+	for _, kv := range resp.Kvs {
+		nodeID, ok := parseSyncNodeKey(string(kv.Key))
+		if !ok {
+			continue
+		}
+
+		node := syncTypes.SyncNodeInfo{
+			NodeID:    nodeID,
+			PublicKey: ed25519.PublicKey(kv.Value),
+			Bootstrap: nodeID == bootstrapID,
+			Indexer:   lo.Contains(indexers, nodeID),
+			Self:      nodeID == s.config.Config().Core.NodeID.String(),
+		}
+
+		if kv.Lease != 0 {
+			ttl, err := s.etcd.TimeToLive(ctx, clientv3.LeaseID(kv.Lease))
+			if err != nil {
+				return nil, err
+			}
+
+			node.LeaseTTL = time.Duration(ttl.TTL) * time.Second
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+// LogView describes the indexers signing the log view and whether enough of them are registered to reach quorum.
+func (s *SyncServiceDefault) LogView(ctx context.Context) (*syncTypes.LogView, error) {
+	indexers := map[string]ed25519.PublicKey{
+		s.config.Config().Core.NodeID.String(): s.nodeKey,
+	}
+	live := map[string]struct{}{
+		string(s.nodeKey): {},
+	}
+
+	if s.etcd != nil {
+		var err error
+		indexers, err = s.indexerKeys(ctx, s.etcd)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes, _, err := fetchSyncNodes(ctx, s.etcd)
+		if err != nil {
+			return nil, err
+		}
+
+		live = make(map[string]struct{}, len(nodes))
+		for _, node := range nodes {
+			live[string(node)] = struct{}{}
+		}
+	}
+
+	view, err := sync.NewView(sync.VIEW_NAME, s.originKey, lo.Values(indexers), s.getConfig().Quorum)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeIDs := make(map[string]string, len(indexers))
+	for nodeID, key := range indexers {
+		nodeIDs[string(key)] = nodeID
+	}
+
+	isLive := func(key ed25519.PublicKey) bool {
+		_, ok := live[string(key)]
+		return ok
+	}
+
+	signers := make([]syncTypes.LogSigner, 0, len(view.Signers))
+	for _, key := range view.Signers {
+		signers = append(signers, syncTypes.LogSigner{
+			NodeID:    nodeIDs[string(key)],
+			PublicKey: key,
+			Live:      isLive(key),
+		})
+	}
+
+	return &syncTypes.LogView{
+		Name:     view.Name,
+		Key:      view.Key,
+		Quorum:   view.Quorum,
+		Signers:  signers,
+		Writable: view.Writable(isLive),
+	}, nil
+}
+
+// RemoveSyncNode drops a node from the writer set and deletes its registration, without waiting for its lease to expire.
+func (s *SyncServiceDefault) RemoveSyncNode(ctx context.Context, nodeID string) error {
+	if s.etcd == nil {
+		return syncTypes.ErrClusterDisabled
+	}
+
+	uuid, err := types.ParseUUID(nodeID)
+	if err != nil {
+		return syncTypes.ErrSyncNodeNotFound
+	}
+
+	key := fmt.Sprintf(ETC_SYNC_PREFIX, uuid.String())
+
+	resp, err := s.etcd.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if resp.Count == 0 {
+		return syncTypes.ErrSyncNodeNotFound
+	}
+
+	err = s.grpcPlugin.RemoveNode(ctx, resp.Kvs[0].Value)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.etcd.Delete(ctx, key)
+
+	return err
+}
+
+// PushSyncNodes sends the full node list from etcd to the sync backend.
+func (s *SyncServiceDefault) PushSyncNodes(ctx context.Context) error {
+	if s.etcd == nil {
+		return syncTypes.ErrClusterDisabled
+	}
+
+	_, err := s.resyncNodes(ctx, s.etcd)
+
+	return err
+}
+
+// resyncNodes replaces the backend's writer set with the nodes registered in etcd, returning the revision the list was read at.
+func (s *SyncServiceDefault) resyncNodes(ctx context.Context, client *clientv3.Client) (int64, error) {
+	nodes, rev, err := fetchSyncNodes(ctx, client)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.grpcPlugin.UpdateNodes(ctx, nodes)
+	if err != nil {
+		return 0, err
+	}
+
+	return rev, nil
+}
+
+// reconcileSyncNodes keeps the backend's writer set in line with etcd for the lifetime of the service. Nodes that register are added and nodes whose key is deleted or expires are removed. If the watch fails, the full list is resynced and watching resumes from there.
+func (s *SyncServiceDefault) reconcileSyncNodes(client *clientv3.Client, rev int64) {
+	for {
+		watchChan := client.Watch(s.ctx, ETC_NODE_PREFIX, clientv3.WithPrefix(), clientv3.WithPrevKV(), clientv3.WithRev(rev+1))
+
+		for watchResp := range watchChan {
+			if err := watchResp.Err(); err != nil {
+				s.logger.Error("sync node watch failed", zap.Error(err))
+				break
+			}
+
+			for _, event := range watchResp.Events {
+				s.applyNodeEvent(client, event)
+			}
+
+			rev = watchResp.Header.Revision
+		}
+
+		for {
+			if s.ctx.Err() != nil {
+				return
+			}
+
+			var err error
+			rev, err = s.resyncNodes(s.ctx, client)
+			if err == nil {
+				break
+			}
+
+			s.logger.Error("failed to resync sync nodes", zap.Error(err))
+
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(nodeResyncDelay):
+			}
+		}
+	}
+}
+
+func (s *SyncServiceDefault) applyNodeEvent(client *clientv3.Client, event *clientv3.Event) {
+	nodeID, ok := parseSyncNodeKey(string(event.Kv.Key))
+	if !ok {
+		return
+	}
+
+	switch event.Type {
+	case clientv3.EventTypePut:
+		s.logger.Info("sync node joined", zap.String("node", nodeID))
+
+		_, err := s.resyncNodes(s.ctx, client)
+		if err != nil {
+			s.logger.Error("failed to add sync node", zap.String("node", nodeID), zap.Error(err))
+		}
+	case clientv3.EventTypeDelete:
+		s.logger.Info("sync node left", zap.String("node", nodeID))
+
+		// Without the previous value the key is unknown, so fall back to replacing the whole writer set.
+		if event.PrevKv == nil {
+			_, err := s.resyncNodes(s.ctx, client)
+			if err != nil {
+				s.logger.Error("failed to remove sync node", zap.String("node", nodeID), zap.Error(err))
+			}
+			return
+		}
+
+		err := s.grpcPlugin.RemoveNode(s.ctx, event.PrevKv.Value)
+		if err != nil {
+			s.logger.Error("failed to remove sync node", zap.String("node", nodeID), zap.Error(err))
+		}
+	}
+}
+
+func (s *SyncServiceDefault) bootstrapNodeID(ctx context.Context) (string, error) {
+	resp, err := s.etcd.Get(ctx, ETC_SYNC_BOOTSTRAP_KEY)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.Count == 0 {
+		return "", nil
+	}
+
+	return string(resp.Kvs[0].Value), nil
+}
+
+// parseSyncNodeKey returns the node ID from a /node/<id>/sync key.
+func parseSyncNodeKey(key string) (string, bool) {
+	if !strings.HasPrefix(key, ETC_NODE_PREFIX) || !strings.HasSuffix(key, ETC_NODE_SYNC_SUFFIX) {
+		return "", false
+	}
+
+	nodeID := strings.TrimSuffix(strings.TrimPrefix(key, ETC_NODE_PREFIX), ETC_NODE_SYNC_SUFFIX)
+
+	return nodeID, nodeID != ""
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"go.lumeweb.com/portal-plugin-sync/internal/metadata"
+	sync "go.lumeweb.com/portal-plugin-sync/internal/p2p"
+	"go.uber.org/zap"
+)
+
+// provenFileMeta returns the entries that can be shown to come from an authorized writer. Each entry's block proof is checked against the writer's core manifest, and the proven block must decode to the same object. Entries without a writer or proof are dropped.
+func (s *SyncServiceDefault) provenFileMeta(ctx context.Context, meta []*metadata.FileMeta) ([]*metadata.FileMeta, error) {
+	writers, err := s.authorizedWriters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proven := make([]*metadata.FileMeta, 0, len(meta))
+
+	for _, m := range meta {
+		if len(m.Writer) == 0 || m.LogProof == nil {
+			s.logger.Debug("log entry without a proof", zap.Binary("hash", m.Hash))
+			continue
+		}
+
+		if _, ok := writers[string(m.Writer)]; !ok {
+			s.logger.Debug("log entry from unauthorized writer", zap.Binary("writer", m.Writer))
+			continue
+		}
+
+		if err := verifyLogProof(m); err != nil {
+			s.logger.Debug("log entry failed verification", zap.Binary("writer", m.Writer), zap.Error(err))
+			continue
+		}
+
+		proven = append(proven, m)
+	}
+
+	return proven, nil
+}
+
+// authorizedWriters returns the keys allowed to write to the log, indexed by their string form.
+func (s *SyncServiceDefault) authorizedWriters(ctx context.Context) (map[string]struct{}, error) {
+	if s.etcd == nil {
+		return map[string]struct{}{string(s.nodeKey): {}}, nil
+	}
+
+	nodes, _, err := fetchSyncNodes(ctx, s.etcd)
+	if err != nil {
+		return nil, err
+	}
+
+	writers := make(map[string]struct{}, len(nodes))
+	for _, node := range nodes {
+		writers[string(node)] = struct{}{}
+	}
+
+	return writers, nil
+}
+
+func verifyLogProof(m *metadata.FileMeta) error {
+	err := sync.VerifyBlock(sync.WriterManifest(m.Writer), m.LogProof)
+	if err != nil {
+		return err
+	}
+
+	block, err := decodeFileMeta(m.LogProof.Value)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(block.Hash, m.Hash) || !bytes.Equal(block.Fingerprint(), m.Fingerprint()) {
+		return sync.ErrInvalidProof
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go.lumeweb.com/portal-plugin-sync/internal/db"
+	syncTypes "go.lumeweb.com/portal-plugin-sync/types"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+// quotaLock serializes this node's quota checks with the import they admit. Across nodes the same is done by locking the user's row with db.LockImportQuota.
+type quotaLock struct {
+	sync.Mutex
+}
+
+func (s *SyncServiceDefault) quotaEnabled() bool {
+	quota := s.getConfig().Quota
+
+	return quota.MaxConcurrent > 0 || quota.MaxPerDay > 0 || quota.MaxBytes > 0
+}
+
+// admitImport checks the user's quota and creates the import record in one transaction. The user's quota row stays locked until the record is written, so no other node can admit an import for them in between.
+func (s *SyncServiceDefault) admitImport(ctx context.Context, record *db.Import) error {
+	s.quotaLock.Lock()
+	defer s.quotaLock.Unlock()
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if s.quotaEnabled() {
+			err := db.LockImportQuota(ctx, tx, record.UserID)
+			if err != nil {
+				return err
+			}
+
+			err = s.checkQuota(ctx, tx, uint64(record.UserID), record.Size)
+			if err != nil {
+				return err
+			}
+		}
+
+		return db.CreateImport(ctx, tx, record)
+	})
+}
+
+// checkQuota returns an error wrapping syncTypes.ErrImportQuotaExceeded if importing size more bytes would put the user over any of their limits.
+func (s *SyncServiceDefault) checkQuota(ctx context.Context, tx *gorm.DB, userID uint64, size uint64) error {
+	quota := s.getConfig().Quota
+
+	if !s.quotaEnabled() {
+		return nil
+	}
+
+	usage, err := db.GetImportUsage(ctx, tx, uint(userID), time.Now().Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+
+	if quota.MaxConcurrent > 0 && usage.Active >= int64(quota.MaxConcurrent) {
+		return fmt.Errorf("%w (limit %d)", syncTypes.ErrImportLimitConcurrent, quota.MaxConcurrent)
+	}
+
+	if quota.MaxPerDay > 0 && usage.Recent >= int64(quota.MaxPerDay) {
+		return fmt.Errorf("%w (limit %d per day)", syncTypes.ErrImportLimitDaily, quota.MaxPerDay)
+	}
+
+	if quota.MaxBytes > 0 && usage.Bytes+size > quota.MaxBytes {
+		return fmt.Errorf("%w (%d of %d bytes used, object is %d bytes)", syncTypes.ErrImportLimitBytes, usage.Bytes, quota.MaxBytes, size)
+	}
+
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"crypto/ed25519"
 	"crypto/sha256"
 	_ "embed"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/gookit/event"
@@ -17,20 +18,24 @@ import (
 	node_server "go.lumeweb.com/portal-plugin-sync-node-server/go"
 	"go.lumeweb.com/portal-plugin-sync/internal/cron"
 	"go.lumeweb.com/portal-plugin-sync/internal/cron/define"
+	"go.lumeweb.com/portal-plugin-sync/internal/db"
+	"go.lumeweb.com/portal-plugin-sync/internal/denylist"
 	"go.lumeweb.com/portal-plugin-sync/internal/metadata"
 	sync "go.lumeweb.com/portal-plugin-sync/internal/p2p"
+	"go.lumeweb.com/portal-plugin-sync/internal/ratelimit"
 	syncTypes "go.lumeweb.com/portal-plugin-sync/types"
 	"go.lumeweb.com/portal/config"
 	"go.lumeweb.com/portal/config/types"
 	"go.lumeweb.com/portal/core"
 	_event "go.lumeweb.com/portal/event"
+	siaTypes "go.sia.tech/core/types"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/hkdf"
+	"gorm.io/gorm"
 	"io"
 	"os"
 	"os/exec"
 	"path"
-	"strings"
 	"time"
 )
 
@@ -47,17 +52,34 @@ const ETC_SYNC_LEADER_ELECTION_KEY = "/sync/leader"
 const syncDataFolder = "sync_data"
 
 type SyncServiceDefault struct {
-	ctx        core.Context
-	config     config.Manager
-	logger     *core.Logger
-	grpcClient *plugin.Client
-	grpcPlugin Sync
-	logKey     []byte
-	renter     core.RenterService
-	storage    core.StorageService
-	metadata   core.MetadataService
-	cron       core.CronService
-	syncCron   *cron.Cron
+	ctx          core.Context
+	config       config.Manager
+	logger       *core.Logger
+	grpcPlugin   Sync
+	supervisor   *SyncSupervisor
+	sidecarDir   string
+	logKey       []byte
+	discoveryKey []byte
+	renter       core.RenterService
+	storage      core.StorageService
+	metadata     core.MetadataService
+	cron         core.CronService
+	syncCron     *cron.Cron
+	db           *gorm.DB
+	knownHosts   map[siaTypes.PublicKey]struct{}
+	limits       stageLimiters
+	quotaLock    quotaLock
+	etcd         *clientv3.Client
+	lease        nodeLease
+	nodeKey      ed25519.PublicKey
+	originKey    ed25519.PublicKey
+}
+
+// stageLimiters rate limit the expensive steps of publishing an upload.
+type stageLimiters struct {
+	metadata *ratelimit.Limiter
+	proof    *ratelimit.Limiter
+	update   *ratelimit.Limiter
 }
 
 type SyncProtocol interface {
@@ -81,6 +103,12 @@ func NewSyncService() (*SyncServiceDefault, []core.ContextBuilderOption, error)
 			_sync.metadata = ctx.Service(core.METADATA_SERVICE).(core.MetadataService)
 			_sync.cron = ctx.Service(core.CRON_SERVICE).(core.CronService)
 			_sync.syncCron = cron.NewCron(ctx)
+			_sync.db = ctx.DB()
+
+			err := _sync.db.AutoMigrate(&db.Import{}, &db.Published{}, &db.ScanCursor{}, &db.PublishRetry{}, &db.Denied{}, &db.ImportQuotaLock{})
+			if err != nil {
+				return err
+			}
 
 			return nil
 		}),
@@ -117,14 +145,77 @@ func (s *SyncServiceDefault) ScheduleJobs(crn core.CronService) error {
 	if err != nil {
 		return err
 	}
+
+	err = crn.CreateJobIfNotExists(define.CronTaskRetryPublishName, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	err = crn.CreateJobIfNotExists(define.CronTaskRemoveDeletedName, nil, nil)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
-func (s *SyncServiceDefault) Update(upload core.UploadMetadata) error {
+// Update publishes an upload to the sync log. If publishing fails the upload is queued for a retry with backoff, and once it succeeds any retry or dead letter left for it is cleared.
+func (s *SyncServiceDefault) Update(ctx context.Context, upload core.UploadMetadata) error {
 	if !s.Enabled() {
 		return nil
 	}
 
+	err := s.update(ctx, upload)
+	if err == nil {
+		if err := db.DeletePublishRetryByHash(ctx, s.db, upload.Hash); err != nil {
+			s.logger.Error("failed to clear publish retry", zap.Error(err))
+		}
+		return nil
+	}
+
+	if ctx.Err() == nil && !errors.Is(err, syncTypes.ErrObjectDenied) {
+		if err := s.queuePublishRetry(ctx, upload, err); err != nil {
+			s.logger.Error("failed to queue publish retry", zap.Error(err))
+		}
+	}
+
+	return err
+}
+
+// queuePublishRetry records a failed publish. If a retry is already queued the failure counts as one of its attempts, so uploads that keep failing in scans back off and become dead letters the same way as in the retry job.
+func (s *SyncServiceDefault) queuePublishRetry(ctx context.Context, upload core.UploadMetadata, cause error) error {
+	record, err := db.GetPublishRetry(ctx, s.db, upload.Hash)
+	if err != nil {
+		return err
+	}
+
+	if record == nil {
+		return db.QueuePublishRetry(ctx, s.db, upload.ID, upload.Hash, upload.Protocol, cause, time.Now().Add(s.retryDelay(1)))
+	}
+
+	if record.Dead {
+		record.LastError = cause.Error()
+	} else {
+		s.failPublishRetry(record, cause)
+	}
+
+	return db.SavePublishRetry(ctx, s.db, record)
+}
+
+// failPublishRetry counts a failed attempt against record, scheduling the next one or marking it dead once it runs out of attempts.
+func (s *SyncServiceDefault) failPublishRetry(record *db.PublishRetry, cause error) {
+	record.Attempts++
+	record.LastError = cause.Error()
+
+	if record.Attempts >= s.getConfig().Retry.MaxAttempts {
+		record.Dead = true
+		s.logger.Warn("publish retries exhausted", zap.Binary("hash", record.Hash), zap.Int("attempts", record.Attempts), zap.Error(cause))
+		return
+	}
+
+	record.NextAttemptAt = time.Now().Add(s.retryDelay(record.Attempts + 1))
+}
+
+func (s *SyncServiceDefault) update(ctx context.Context, upload core.UploadMetadata) error {
 	proto := core.GetProtocol(upload.Protocol)
 
 	if proto == nil {
@@ -138,7 +229,22 @@ func (s *SyncServiceDefault) Update(upload core.UploadMetadata) error {
 
 	fileName := syncProto.EncodeFileName(upload.Hash)
 
-	object, err := s.renter.GetObjectMetadata(s.ctx, upload.Protocol, fileName)
+	denied, err := db.IsDenied(ctx, s.db, upload.Hash)
+	if err != nil {
+		return err
+	}
+
+	if denied {
+		s.logger.Debug("object is on the denylist", zap.String("hash", fileName))
+		return syncTypes.ErrObjectDenied
+	}
+
+	err = s.limits.metadata.Wait(ctx)
+	if err != nil {
+		return err
+	}
+
+	object, err := s.renter.GetObjectMetadata(ctx, upload.Protocol, fileName)
 	if err != nil {
 		return err
 	}
@@ -150,155 +256,638 @@ func (s *SyncServiceDefault) Update(upload core.UploadMetadata) error {
 			noShards = true
 			break
 		}
-	}
+	}
+
+	if noShards {
+		s.logger.Debug("object has at-least one slab with no shards", zap.String("hash", fileName))
+		return syncTypes.ErrObjectNoShards
+	}
+
+	fingerprint := metadata.Fingerprint(object.Key, object.Slabs)
+
+	published, err := db.GetPublished(ctx, s.db, upload.Hash)
+	if err != nil {
+		return err
+	}
+
+	if published != nil && bytes.Equal(published.Fingerprint, fingerprint) {
+		s.logger.Debug("object already published", zap.String("hash", fileName))
+		return nil
+	}
+
+	err = s.limits.proof.Wait(ctx)
+	if err != nil {
+		return err
+	}
+
+	proofReader, err := s.storage.DownloadObjectProof(ctx, syncProto, upload.Hash)
+
+	if err != nil {
+		return err
+	}
+
+	proof, err := io.ReadAll(proofReader)
+	_ = proofReader.Close()
+	if err != nil {
+		return err
+	}
+
+	meta := metadata.FileMeta{
+		Hash:      upload.Hash,
+		Proof:     proof,
+		Multihash: nil,
+		Protocol:  upload.Protocol,
+		Key:       object.Key,
+		Size:      uint64(object.Size),
+		Slabs:     object.Slabs,
+	}
+
+	err = s.limits.update.Wait(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = s.grpcPlugin.Update(ctx, meta)
+
+	if err != nil {
+		return err
+	}
+
+	err = db.SavePublished(ctx, s.db, upload.ID, upload.Hash, upload.Protocol, fingerprint)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Remove withdraws a hash from the sync log by writing a tombstone, and forgets any publish state kept for it.
+func (s *SyncServiceDefault) Remove(ctx context.Context, hash []byte) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	protocol := ""
+
+	published, err := db.GetPublished(ctx, s.db, hash)
+	if err != nil {
+		return err
+	}
+
+	if published != nil {
+		protocol = published.Protocol
+	} else if upload, err := s.metadata.GetUpload(ctx, hash); err == nil && !upload.IsEmpty() {
+		protocol = upload.Protocol
+	}
+
+	return s.remove(ctx, hash, protocol)
+}
+
+// remove tombstones hash under the same lookups Update indexes it by. When the protocol it was published under is unknown, a tombstone is written for every sync protocol so Import finds it whichever identifier it is queried by.
+func (s *SyncServiceDefault) remove(ctx context.Context, hash []byte, protocol string) error {
+	protocols := []string{protocol}
+	if protocol == "" {
+		protocols = syncProtocolNames()
+	}
+
+	for _, p := range protocols {
+		err := s.limits.update.Wait(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = s.grpcPlugin.Remove(ctx, hash, p)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := db.DeletePublished(ctx, s.db, hash)
+	if err != nil {
+		return err
+	}
+
+	return db.DeletePublishRetryByHash(ctx, s.db, hash)
+}
+
+// RemoveDeleted tombstones every hash this portal published whose upload has since been deleted. The portal does not announce deletions, so they are found by comparing the publish records against the uploads table.
+func (s *SyncServiceDefault) RemoveDeleted(ctx context.Context) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	var after uint
+	for {
+		records, err := db.GetDeletedPublished(ctx, s.db, after, define.CronTaskScanObjectsPageSize)
+		if err != nil {
+			return err
+		}
+
+		if len(records) == 0 {
+			return nil
+		}
+
+		for _, record := range records {
+			err := s.remove(ctx, record.Hash, record.Protocol)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				s.logger.Error("failed to remove deleted upload", zap.Error(err), zap.Binary("hash", record.Hash))
+			}
+		}
+
+		after = records[len(records)-1].ID
+	}
+}
+
+// RetryPublishes retries every due publish. Uploads that have been deleted are dropped from the queue, and ones that run out of attempts become dead letters.
+func (s *SyncServiceDefault) RetryPublishes(ctx context.Context) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	retryConfig := s.getConfig().Retry
+
+	due, err := db.DuePublishRetries(ctx, s.db, time.Now(), retryConfig.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range due {
+		upload, err := s.metadata.GetUpload(ctx, record.Hash)
+		if err == nil && upload.IsEmpty() {
+			if err := db.DeletePublishRetry(ctx, s.db, record.ID); err != nil {
+				s.logger.Error("failed to delete publish retry", zap.Error(err))
+			}
+			continue
+		}
+
+		if err == nil {
+			err = s.update(ctx, upload)
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err == nil || errors.Is(err, syncTypes.ErrObjectDenied) {
+			if err := db.DeletePublishRetry(ctx, s.db, record.ID); err != nil {
+				s.logger.Error("failed to delete publish retry", zap.Error(err))
+			}
+			continue
+		}
+
+		s.failPublishRetry(&record, err)
+
+		if err := db.SavePublishRetry(ctx, s.db, &record); err != nil {
+			s.logger.Error("failed to save publish retry", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+func (s *SyncServiceDefault) ListDeadLetters(ctx context.Context) ([]syncTypes.PublishRetryInfo, error) {
+	records, err := db.ListDeadPublishRetries(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.Map(records, func(record db.PublishRetry, _ int) syncTypes.PublishRetryInfo {
+		return record.ToInfo()
+	}), nil
+}
+
+func (s *SyncServiceDefault) RequeueDeadLetter(ctx context.Context, id uint) error {
+	return db.RequeuePublishRetry(ctx, s.db, id, time.Now())
+}
+
+func (s *SyncServiceDefault) IsDenied(ctx context.Context, hash []byte) (bool, error) {
+	return db.IsDenied(ctx, s.db, hash)
+}
+
+// Deny adds a hash to the denylist and withdraws it from the sync log if this node published it.
+func (s *SyncServiceDefault) Deny(ctx context.Context, hash []byte, reason string) error {
+	err := db.SaveDenied(ctx, s.db, []db.Denied{{Hash: hash, Reason: reason}})
+	if err != nil {
+		return err
+	}
+
+	return s.withdrawDenied(ctx, [][]byte{hash})
+}
+
+func (s *SyncServiceDefault) Allow(ctx context.Context, hash []byte) error {
+	return db.DeleteDenied(ctx, s.db, hash)
+}
+
+func (s *SyncServiceDefault) ListDenied(ctx context.Context) ([]syncTypes.DenylistEntry, error) {
+	records, err := db.ListDenied(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.Map(records, func(record db.Denied, _ int) syncTypes.DenylistEntry {
+		return record.ToInfo()
+	}), nil
+}
+
+// ImportDenylist adds every hash from a line-based denylist, returning how many entries were read.
+func (s *SyncServiceDefault) ImportDenylist(ctx context.Context, r io.Reader) (int, error) {
+	entries, err := denylist.Parse(r)
+	if err != nil {
+		return 0, err
+	}
+
+	records := lo.Map(entries, func(entry denylist.Entry, _ int) db.Denied {
+		return db.Denied{Hash: entry.Hash, Reason: entry.Reason}
+	})
+
+	err = db.SaveDenied(ctx, s.db, records)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.withdrawDenied(ctx, lo.Map(entries, func(entry denylist.Entry, _ int) []byte {
+		return entry.Hash
+	}))
+	if err != nil {
+		return 0, err
+	}
+
+	return len(entries), nil
+}
+
+// withdrawDenied tombstones any of the given hashes that this node has published.
+func (s *SyncServiceDefault) withdrawDenied(ctx context.Context, hashes [][]byte) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	for _, hash := range hashes {
+		published, err := db.GetPublished(ctx, s.db, hash)
+		if err != nil {
+			return err
+		}
+
+		if published == nil {
+			continue
+		}
+
+		err = s.remove(ctx, hash, published.Protocol)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SyncServiceDefault) loadDenylistFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	count, err := s.ImportDenylist(s.ctx, file)
+	if err != nil {
+		return fmt.Errorf("failed to load denylist %s: %w", path, err)
+	}
+
+	s.logger.Info("loaded denylist", zap.String("path", path), zap.Int("entries", count))
+
+	return nil
+}
+
+func (s *SyncServiceDefault) IsAdmin(userID uint64) bool {
+	return lo.Contains(s.getConfig().Admins, uint(userID))
+}
+
+// retryDelay is the backoff before the given attempt, doubling from the base delay up to the max delay.
+func (s *SyncServiceDefault) retryDelay(attempt int) time.Duration {
+	retryConfig := s.getConfig().Retry
+
+	delay := retryConfig.BaseDelay
+	for i := 1; i < attempt && delay < retryConfig.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > retryConfig.MaxDelay {
+		delay = retryConfig.MaxDelay
+	}
+
+	return delay
+}
+
+// LogKey returns the autobase key of the cluster's log. It is derived from the bootstrap node and is the same on every node, regardless of which nodes currently index the log.
+func (s *SyncServiceDefault) LogKey() []byte {
+	return s.logKey
+}
+
+// DiscoveryKey returns the key peers announce on the DHT to find and replicate the log.
+func (s *SyncServiceDefault) DiscoveryKey() []byte {
+	return s.discoveryKey
+}
+
+func (s *SyncServiceDefault) Import(ctx context.Context, object string, uploaderID uint64) (uint, error) {
+	syncProto, hash, err := resolveIdentifier(object)
+	if err != nil {
+		return 0, err
+	}
+
+	meta, err := s.grpcPlugin.Query(ctx, []string{object})
+	if err != nil {
+		return 0, err
+	}
+
+	meta = metadata.FilterTombstoned(meta)
+
+	return s.importObject(ctx, object, syncProto, hash, meta, uploaderID)
+}
+
+// EstimateImport runs the same checks as Import without queuing anything, and estimates what importing the object would cost.
+func (s *SyncServiceDefault) EstimateImport(ctx context.Context, object string, uploaderID uint64) (*syncTypes.ImportEstimate, error) {
+	syncProto, hash, err := resolveIdentifier(object)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := s.grpcPlugin.Query(ctx, []string{object})
+	if err != nil {
+		return nil, err
+	}
+
+	metaDeref, err := s.importCandidates(ctx, hash, metadata.FilterTombstoned(meta))
+	if err != nil {
+		return nil, err
+	}
+
+	best := metaDeref[0]
+
+	err = s.checkQuota(ctx, s.db, uploaderID, best.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unless verify and upload share a stream, the object is downloaded once to verify it and again to upload it.
+	downloads := uint64(2)
+	if s.getConfig().StreamingImport {
+		downloads = 1
+	}
+
+	return &syncTypes.ImportEstimate{
+		Object:        object,
+		Hash:          hash,
+		Protocol:      syncProto.Name(),
+		Size:          best.Size,
+		DownloadBytes: best.DownloadSize() * downloads,
+		StorageBytes:  best.Size,
+	}, nil
+}
+
+func (s *SyncServiceDefault) ImportBatch(ctx context.Context, objects []string, uploaderID uint64) ([]syncTypes.ImportResult, error) {
+	type candidate struct {
+		index int
+		proto SyncProtocol
+		hash  []byte
+	}
+
+	results := make([]syncTypes.ImportResult, len(objects))
+	candidates := make([]candidate, 0, len(objects))
+	keys := make([]string, 0, len(objects))
+	seen := make(map[string]struct{})
+
+	for i, object := range objects {
+		results[i].Object = object
+
+		syncProto, hash, err := resolveIdentifier(object)
+		if err != nil {
+			results[i].Reason, results[i].Error = importRejection(err)
+			continue
+		}
+
+		hashHex := hex.EncodeToString(hash)
+		if _, ok := seen[hashHex]; ok {
+			results[i].Reason = syncTypes.ImportRejectDuplicate
+			continue
+		}
+		seen[hashHex] = struct{}{}
+
+		candidates = append(candidates, candidate{index: i, proto: syncProto, hash: hash})
+		keys = append(keys, object)
+	}
+
+	if len(candidates) == 0 {
+		return results, nil
+	}
+
+	meta, err := s.grpcPlugin.Query(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	meta = metadata.FilterTombstoned(meta)
+
+	metaByHash := lo.GroupBy(meta, func(m *metadata.FileMeta) string {
+		return hex.EncodeToString(m.Hash)
+	})
+
+	for _, c := range candidates {
+		id, err := s.importObject(ctx, objects[c.index], c.proto, c.hash, metaByHash[hex.EncodeToString(c.hash)], uploaderID)
+		if err != nil {
+			results[c.index].Reason, results[c.index].Error = importRejection(err)
+			continue
+		}
+
+		results[c.index].ImportID = id
+	}
+
+	return results, nil
+}
+
+// importCandidates checks the entries found for an object and returns the usable ones, best candidate first.
+func (s *SyncServiceDefault) importCandidates(ctx context.Context, hash []byte, meta []*metadata.FileMeta) ([]metadata.FileMeta, error) {
+	denied, err := db.IsDenied(ctx, s.db, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if denied {
+		return nil, syncTypes.ErrObjectDenied
+	}
+
+	meta = lo.Filter(meta, func(m *metadata.FileMeta, _ int) bool {
+		return bytes.Equal(m.Hash, hash)
+	})
+
+	if len(meta) == 0 {
+		return nil, syncTypes.ErrObjectNotFound
+	}
+
+	if s.getConfig().requireProof() {
+		meta, err = s.provenFileMeta(ctx, meta)
+		if err != nil {
+			return nil, err
+		}
 
-	if noShards {
-		s.logger.Debug("object has at-least one slab with no shards", zap.String("hash", fileName))
-		return nil
+		if len(meta) == 0 {
+			return nil, syncTypes.ErrObjectUnproven
+		}
 	}
 
-	proofReader, err := s.storage.DownloadObjectProof(s.ctx, syncProto, upload.Hash)
+	meta = lo.Filter(meta, func(m *metadata.FileMeta, _ int) bool {
+		return !hasShardlessSlab(m)
+	})
 
-	if err != nil {
-		return err
+	if len(meta) == 0 {
+		return nil, syncTypes.ErrObjectNoShards
 	}
 
-	proof, err := io.ReadAll(proofReader)
+	_upload, err := s.metadata.GetUpload(ctx, hash)
+	if err == nil || !_upload.IsEmpty() {
+		return nil, syncTypes.ErrObjectExists
+	}
 
-	meta := metadata.FileMeta{
-		Hash:      upload.Hash,
-		Proof:     proof,
-		Multihash: nil,
-		Protocol:  upload.Protocol,
-		Key:       object.Key,
-		Size:      uint64(object.Size),
-		Slabs:     object.Slabs,
+	metaDeref := make([]metadata.FileMeta, 0)
+	for _, m := range meta {
+		metaDeref = append(metaDeref, *m)
 	}
 
-	err = s.grpcPlugin.Update(meta)
+	metadata.RankFileMeta(metaDeref, s.knownHosts)
 
-	if err != nil {
-		return err
+	maxSize := s.getConfig().MaxImportSize
+	if maxSize > 0 && metaDeref[0].Size > maxSize {
+		return nil, fmt.Errorf("%w (%d bytes, limit %d)", syncTypes.ErrObjectTooLarge, metaDeref[0].Size, maxSize)
 	}
 
-	return nil
-}
-
-func (s *SyncServiceDefault) LogKey() []byte {
-	return s.logKey
+	return metaDeref, nil
 }
 
-func (s *SyncServiceDefault) Import(object string, uploaderID uint64) error {
-	protos := core.GetProtocols()
-	ctx := context.Background()
-	for _, proto := range protos {
-		syncProto, ok := proto.(SyncProtocol)
-		if !ok {
-			continue
-		}
+// importObject queues verification of the usable candidates found for an object, returning the new import ID.
+func (s *SyncServiceDefault) importObject(ctx context.Context, object string, syncProto SyncProtocol, hash []byte, meta []*metadata.FileMeta, uploaderID uint64) (uint, error) {
+	metaDeref, err := s.importCandidates(ctx, hash, meta)
+	if err != nil {
+		return 0, err
+	}
 
-		if syncProto.ValidIdentifier(object) {
-			hash, err := syncProto.HashFromIdentifier(object)
-			if err != nil {
-				return err
-			}
-			meta, err := s.grpcPlugin.Query([]string{object})
+	record := &db.Import{
+		UserID:   uint(uploaderID),
+		Hash:     hash,
+		Object:   object,
+		Protocol: syncProto.Name(),
+		Size:     metaDeref[0].Size,
+	}
 
-			if err != nil {
-				return err
-			}
+	err = s.admitImport(ctx, record)
+	if err != nil {
+		return 0, err
+	}
 
-			meta = lo.Filter(meta, func(m *metadata.FileMeta, _ int) bool {
-				noShards := false
-				for _, slab := range m.Slabs {
-					if len(slab.Shards) == 0 {
-						noShards = true
-						break
-					}
-				}
+	task := define.CronTaskVerifyObjectName
+	if s.getConfig().StreamingImport {
+		task = define.CronTaskVerifyUploadObjectName
+	}
 
-				return !noShards
-			})
+	err = s.cron.CreateJobIfNotExists(task, define.CronTaskVerifyObjectArgs{
+		Hash:       hash,
+		Object:     metaDeref,
+		UploaderID: uploaderID,
+		ImportID:   record.ID,
+	}, []string{object})
+	if err != nil {
+		if err := db.UpdateImportStatus(ctx, s.db, record.ID, syncTypes.ImportStatusFailed, err); err != nil {
+			s.logger.Error("failed to update import status", zap.Error(err))
+		}
+		return 0, err
+	}
 
-			if len(meta) == 0 {
-				return errors.New("object not found")
-			}
+	return record.ID, nil
+}
 
-			_upload, err := s.metadata.GetUpload(ctx, hash)
-			if err == nil || !_upload.IsEmpty() {
-				return errors.New("object already exists")
-			}
+func (s *SyncServiceDefault) Lookup(ctx context.Context, object string) ([]syncTypes.ObjectInfo, error) {
+	_, hash, err := resolveIdentifier(object)
+	if err != nil {
+		return nil, err
+	}
 
-			metaDeref := make([]metadata.FileMeta, 0)
-			for _, m := range meta {
-				metaDeref = append(metaDeref, *m)
-			}
+	meta, err := s.grpcPlugin.Query(ctx, []string{object})
+	if err != nil {
+		return nil, err
+	}
 
-			err = s.cron.CreateJobIfNotExists(define.CronTaskVerifyObjectName, define.CronTaskVerifyObjectArgs{
-				Hash:       hash,
-				Object:     metaDeref,
-				UploaderID: uploaderID,
-			}, []string{object})
-			if err != nil {
-				return err
-			}
+	meta = lo.Filter(metadata.FilterTombstoned(meta), func(m *metadata.FileMeta, _ int) bool {
+		return bytes.Equal(m.Hash, hash)
+	})
 
-			return nil
-		}
+	if len(meta) == 0 {
+		return nil, syncTypes.ErrObjectNotFound
 	}
 
-	return errors.New("invalid object")
+	return lo.Map(meta, func(m *metadata.FileMeta, _ int) syncTypes.ObjectInfo {
+		return objectInfo(m)
+	}), nil
 }
 
-func (s *SyncServiceDefault) init() error {
-	s.cron.RegisterEntity(s.syncCron)
-	extractDir, err := os.MkdirTemp(os.TempDir(), "")
+func (s *SyncServiceDefault) GetImport(ctx context.Context, id uint) (*syncTypes.ImportInfo, error) {
+	record, err := db.GetImport(ctx, s.db, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = unzip(node_server.GetBundle(), extractDir, s.logger)
+	info := record.ToInfo()
+
+	return &info, nil
+}
 
+func (s *SyncServiceDefault) ListImports(ctx context.Context, userID uint64) ([]syncTypes.ImportInfo, error) {
+	records, err := db.ListImports(ctx, s.db, uint(userID))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	nodePath := path.Join(extractDir, "app", "node")
-	appPath := path.Join(extractDir, "app", "app", "app", "bundle.js")
+	return lo.Map(records, func(record db.Import, _ int) syncTypes.ImportInfo {
+		return record.ToInfo()
+	}), nil
+}
 
-	err = os.Chmod(nodePath, 0755)
+func (s *SyncServiceDefault) init() error {
+	s.cron.RegisterEntity(s.syncCron)
+
+	knownHosts, err := parseKnownHosts(s.getConfig().KnownHosts)
 	if err != nil {
 		return err
 	}
 
-	cmd := exec.Command(nodePath, appPath)
-	cmd.Env = append(os.Environ(), "NODE_NO_WARNINGS=1")
-	cmd.Dir = extractDir
-	clientInst := plugin.NewClient(&plugin.ClientConfig{
-		HandshakeConfig: plugin.HandshakeConfig{
-			ProtocolVersion: 1,
-		},
-		Plugins: plugin.PluginSet{
-			"sync": &SyncGrpcPlugin{},
-		},
-		Cmd:              cmd,
-		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
-	})
-
-	s.grpcClient = clientInst
-
-	rpcClient, err := clientInst.Client()
-	if err != nil {
+	s.knownHosts = knownHosts
 
-		return err
+	if quorum := s.getConfig().Quorum; quorum < 0 {
+		return fmt.Errorf("%w: got %d", ErrInvalidQuorum, quorum)
 	}
 
-	pluginInst, err := rpcClient.Dispense("sync")
-	if err != nil {
-		return err
+	scanConfig := s.getConfig().Scan
+	s.limits = stageLimiters{
+		metadata: ratelimit.New(scanConfig.MetadataRate),
+		proof:    ratelimit.New(scanConfig.ProofRate),
+		update:   ratelimit.New(scanConfig.UpdateRate),
 	}
 
-	s.grpcPlugin = pluginInst.(Sync)
+	switch backend := s.getConfig().Backend; backend {
+	case BACKEND_NATIVE:
+		if s.config.Config().Core.ClusterEnabled() {
+			return ErrNativeClustered
+		}
+		s.grpcPlugin = NewSyncNative()
+	case BACKEND_NODE, "":
+		err = s.startSidecar()
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown sync backend %q", backend)
+	}
 
 	dataDir := path.Join(path.Dir(s.config.ConfigFile()), syncDataFolder)
 
@@ -320,6 +909,8 @@ func (s *SyncServiceDefault) init() error {
 			return err
 		}
 
+		s.etcd = client
+
 		// Check if the bootstrap key exists
 		resp, err := client.Get(context.Background(), ETC_SYNC_BOOTSTRAP_KEY)
 		if err != nil {
@@ -386,7 +977,7 @@ func (s *SyncServiceDefault) init() error {
 		}
 	}
 
-	var logPubKey ed25519.PublicKey
+	originKey := nodeKey.Public().(ed25519.PublicKey)
 
 	if !bootstrap {
 		var bootstrapNodeId string
@@ -417,52 +1008,67 @@ func (s *SyncServiceDefault) init() error {
 			boostrapNodeKey = nodeKey
 		}
 
-		logPubKey, err = sync.NodeKey(boostrapNodeKey.Public(), nil)
-		if err != nil {
-			return err
-		}
+		originKey = boostrapNodeKey.Public().(ed25519.PublicKey)
+	}
+
+	// Every node, the bootstrap node included, opens the log under the bootstrap node's core key
+	logPubKey, err := sync.NodeKey(originKey, nil)
+	if err != nil {
+		return err
+	}
+
+	err = s.grpcPlugin.Init(s.ctx, logPubKey, nodeKey, dataDir)
+	if err != nil {
+		return err
 	}
 
-	err = s.grpcPlugin.Init(logPubKey, nodeKey, dataDir)
+	s.nodeKey = nodeKey.Public().(ed25519.PublicKey)
+	s.originKey = originKey
+
+	// The log is always keyed by the bootstrap node that created it, so every node reports the same key even after indexing has moved elsewhere.
+	s.logKey, err = sync.AutoBaseKey(originKey)
 	if err != nil {
 		return err
 	}
 
-	s.logKey = sync.AutoBaseKey(nodeKey.Public().(ed25519.PublicKey))
+	s.discoveryKey = sync.DiscoveryKey(s.logKey)
 
 	if s.config.Config().Core.ClusterEnabled() {
-		lease := clientv3.NewLease(client)
-		ttl := int64((time.Hour * 24).Seconds())
-		grantResp, err := lease.Grant(context.Background(), ttl) // 60 seconds TTL
+		pubKey := nodeKey.Public().(ed25519.PublicKey)
+		err = s.registerNode(s.ctx, client, fmt.Sprintf(ETC_SYNC_PREFIX, s.config.Config().Core.NodeID.String()), string(pubKey))
 		if err != nil {
 			return err
 		}
 
-		pubKey := nodeKey.Public().(ed25519.PublicKey)
-		_, err = client.Put(context.Background(), fmt.Sprintf(ETC_SYNC_PREFIX, s.config.Config().Core.NodeID.String()), string(pubKey), clientv3.WithLease(grantResp.ID))
+		rev, err := s.resyncNodes(s.ctx, client)
 		if err != nil {
 			return err
 		}
 
-		nodes, err := fetchSyncNodes(client)
+		if s.supervisor != nil {
+			s.supervisor.OnRestart(func(ctx context.Context, _sync Sync) error {
+				nodes, _, err := fetchSyncNodes(ctx, client)
+				if err != nil {
+					return err
+				}
+
+				return _sync.UpdateNodes(ctx, nodes)
+			})
+		}
+
+		err = s.migrateIndexers(s.ctx, client)
 		if err != nil {
 			return err
 		}
 
-		err = s.grpcPlugin.UpdateNodes(nodes)
+		go s.reconcileSyncNodes(client, rev)
+	}
 
+	if path := s.getConfig().DenylistFile; path != "" {
+		err = s.loadDenylistFile(path)
 		if err != nil {
 			return err
 		}
-
-		go watchExpiringNodes(client, s.logger, func(nodeID types.UUID) {
-			if nodeID == s.config.Config().Core.NodeID {
-				err := s.grpcPlugin.RemoveNode(pubKey)
-				if err != nil {
-					s.logger.Error("failed to remove node", zap.Error(err))
-				}
-			}
-		})
 	}
 
 	s.ctx.Event().On(_event.EVENT_STORAGE_OBJECT_UPLOADED, event.ListenerFunc(func(event event.Event) error {
@@ -472,7 +1078,7 @@ func (s *SyncServiceDefault) init() error {
 		}
 
 		upload := evt.ObjectMetadata()
-		err := s.Update(*upload)
+		err := s.Update(s.ctx, *upload)
 		if err != nil {
 			s.logger.Error("failed to update object", zap.Error(err))
 			return err
@@ -484,12 +1090,209 @@ func (s *SyncServiceDefault) init() error {
 	return nil
 }
 
+func (s *SyncServiceDefault) startSidecar() error {
+	extractDir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		return err
+	}
+
+	s.sidecarDir = extractDir
+
+	err = unzip(node_server.GetBundle(), extractDir, s.logger)
+
+	if err != nil {
+		return err
+	}
+
+	nodePath := path.Join(extractDir, "app", "node")
+	appPath := path.Join(extractDir, "app", "app", "app", "bundle.js")
+
+	err = os.Chmod(nodePath, 0755)
+	if err != nil {
+		return err
+	}
+
+	supervisor := NewSyncSupervisor(func() (*plugin.Client, Sync, error) {
+		return launchSidecar(nodePath, appPath, extractDir, s.getConfig().Timeouts)
+	}, s.logger)
+
+	err = supervisor.Start()
+	if err != nil {
+		return err
+	}
+
+	s.supervisor = supervisor
+	s.grpcPlugin = supervisor
+
+	return nil
+}
+
 func (s *SyncServiceDefault) stop() error {
+	if s.etcd != nil {
+		s.deregisterNode()
+	}
+
+	if closer, ok := s.grpcPlugin.(io.Closer); ok {
+		err := closer.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.sidecarDir != "" {
+		err := os.RemoveAll(s.sidecarDir)
+		if err != nil {
+			s.logger.Error("failed to remove sidecar directory", zap.Error(err))
+		}
+	}
+
 	return nil
 }
 
 func (s *SyncServiceDefault) Enabled() bool {
-	return s.config.GetService(syncTypes.SYNC_SERVICE).(*ServiceConfig).Enabled
+	return s.getConfig().Enabled
+}
+
+func (s *SyncServiceDefault) getConfig() *ServiceConfig {
+	return s.config.GetService(syncTypes.SYNC_SERVICE).(*ServiceConfig)
+}
+
+func launchSidecar(nodePath string, appPath string, dir string, timeouts TimeoutConfig) (*plugin.Client, Sync, error) {
+	cmd := exec.Command(nodePath, appPath)
+	cmd.Env = append(os.Environ(), "NODE_NO_WARNINGS=1")
+	cmd.Dir = dir
+	clientInst := plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig: plugin.HandshakeConfig{
+			ProtocolVersion: 1,
+		},
+		Plugins: plugin.PluginSet{
+			"sync": &SyncGrpcPlugin{timeouts: timeouts},
+		},
+		Cmd:              cmd,
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+	})
+
+	rpcClient, err := clientInst.Client()
+	if err != nil {
+		clientInst.Kill()
+		return nil, nil, err
+	}
+
+	pluginInst, err := rpcClient.Dispense("sync")
+	if err != nil {
+		clientInst.Kill()
+		return nil, nil, err
+	}
+
+	return clientInst, pluginInst.(Sync), nil
+}
+
+func syncProtocolNames() []string {
+	var names []string
+
+	for _, proto := range core.GetProtocols() {
+		if syncProto, ok := proto.(SyncProtocol); ok {
+			names = append(names, syncProto.Name())
+		}
+	}
+
+	return names
+}
+
+func resolveIdentifier(object string) (SyncProtocol, []byte, error) {
+	for _, proto := range core.GetProtocols() {
+		syncProto, ok := proto.(SyncProtocol)
+		if !ok {
+			continue
+		}
+
+		if syncProto.ValidIdentifier(object) {
+			hash, err := syncProto.HashFromIdentifier(object)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			return syncProto, hash, nil
+		}
+	}
+
+	return nil, nil, syncTypes.ErrInvalidIdentifier
+}
+
+func parseKnownHosts(hosts []string) (map[siaTypes.PublicKey]struct{}, error) {
+	knownHosts := make(map[siaTypes.PublicKey]struct{}, len(hosts))
+
+	for _, host := range hosts {
+		var pk siaTypes.PublicKey
+		err := pk.UnmarshalText([]byte(host))
+		if err != nil {
+			return nil, fmt.Errorf("invalid known host %q: %w", host, err)
+		}
+
+		knownHosts[pk] = struct{}{}
+	}
+
+	return knownHosts, nil
+}
+
+func hasShardlessSlab(meta *metadata.FileMeta) bool {
+	for _, slab := range meta.Slabs {
+		if len(slab.Shards) == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// objectInfo summarizes a FileMeta. Health is that of the weakest slab, MinShards the most any slab needs and Shards the fewest any slab has.
+func objectInfo(meta *metadata.FileMeta) syncTypes.ObjectInfo {
+	info := syncTypes.ObjectInfo{
+		Hash:      meta.Hash,
+		Protocol:  meta.Protocol,
+		Size:      meta.Size,
+		SlabCount: len(meta.Slabs),
+		Aliases:   meta.Aliases,
+	}
+
+	for i, slab := range meta.Slabs {
+		if i == 0 || slab.Health < info.Health {
+			info.Health = slab.Health
+		}
+
+		if slab.MinShards > info.MinShards {
+			info.MinShards = slab.MinShards
+		}
+
+		if i == 0 || len(slab.Shards) < info.Shards {
+			info.Shards = len(slab.Shards)
+		}
+	}
+
+	return info
+}
+
+func importRejection(err error) (syncTypes.ImportRejectReason, string) {
+	switch {
+	case errors.Is(err, syncTypes.ErrInvalidIdentifier):
+		return syncTypes.ImportRejectInvalidIdentifier, err.Error()
+	case errors.Is(err, syncTypes.ErrObjectNotFound):
+		return syncTypes.ImportRejectNotFound, err.Error()
+	case errors.Is(err, syncTypes.ErrObjectExists):
+		return syncTypes.ImportRejectAlreadyExists, err.Error()
+	case errors.Is(err, syncTypes.ErrObjectNoShards):
+		return syncTypes.ImportRejectNoShards, err.Error()
+	case errors.Is(err, syncTypes.ErrObjectDenied):
+		return syncTypes.ImportRejectDenied, err.Error()
+	case errors.Is(err, syncTypes.ErrImportQuotaExceeded):
+		return syncTypes.ImportRejectQuotaExceeded, err.Error()
+	case errors.Is(err, syncTypes.ErrObjectTooLarge):
+		return syncTypes.ImportRejectTooLarge, err.Error()
+	case errors.Is(err, syncTypes.ErrObjectUnproven):
+		return syncTypes.ImportRejectUnproven, err.Error()
+	default:
+		return syncTypes.ImportRejectError, err.Error()
+	}
 }
 
 func unzip(data []byte, dest string, logger *core.Logger) error {
@@ -528,34 +1331,20 @@ func unzip(data []byte, dest string, logger *core.Logger) error {
 	return nil
 }
 
-func fetchSyncNodes(client *clientv3.Client) ([]ed25519.PublicKey, error) {
-	resp, err := client.Get(context.Background(), ETC_NODE_PREFIX, clientv3.WithPrefix())
+// fetchSyncNodes returns the public keys of every registered sync node and the etcd revision they were read at.
+func fetchSyncNodes(ctx context.Context, client *clientv3.Client) ([]ed25519.PublicKey, int64, error) {
+	resp, err := client.Get(ctx, ETC_NODE_PREFIX, clientv3.WithPrefix())
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	var syncNodes []ed25519.PublicKey
 	for _, kv := range resp.Kvs {
+		if _, ok := parseSyncNodeKey(string(kv.Key)); !ok {
+			continue
+		}
 		syncNodes = append(syncNodes, kv.Value)
 	}
 
-	return syncNodes, nil
-}
-func watchExpiringNodes(client *clientv3.Client, logger *core.Logger, onExpire func(nodeID types.UUID)) {
-	watchChan := client.Watch(context.Background(), "/node/", clientv3.WithPrefix())
-
-	for watchResp := range watchChan {
-		for _, event := range watchResp.Events {
-			if event.Type == clientv3.EventTypeDelete {
-				nodeID := strings.TrimPrefix(string(event.Kv.Key), ETC_NODE_PREFIX)
-				nodeID = strings.TrimSuffix(nodeID, ETC_NODE_SYNC_SUFFIX)
-				nodeUUID, err := types.ParseUUID(nodeID)
-				if err != nil {
-					logger.Error("failed to parse node ID", zap.Error(err))
-					continue
-				}
-				onExpire(nodeUUID)
-			}
-		}
-	}
+	return syncNodes, resp.Header.Revision, nil
 }
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"github.com/hashicorp/go-plugin"
+	"go.lumeweb.com/portal-plugin-sync/internal/metadata"
+	"go.lumeweb.com/portal/core"
+	"go.uber.org/zap"
+	"io"
+	"sync"
+	"time"
+)
+
+var _ Sync = (*SyncSupervisor)(nil)
+var _ io.Closer = (*SyncSupervisor)(nil)
+
+var ErrSidecarUnavailable = errors.New("sync sidecar is not running")
+
+const (
+	supervisorPollInterval = time.Second
+	supervisorMinBackoff   = time.Second
+	supervisorMaxBackoff   = time.Minute
+)
+
+// SidecarLauncher starts a new sidecar process and returns its client and the dispensed Sync implementation.
+type SidecarLauncher func() (*plugin.Client, Sync, error)
+
+type initArgs struct {
+	logPublicKey   ed25519.PublicKey
+	nodePrivateKey ed25519.PrivateKey
+	dataDir        string
+}
+
+// SyncSupervisor keeps the sidecar running. It restarts the process with exponential backoff when it exits, replays Init and runs the restart hooks before the new process serves calls.
+type SyncSupervisor struct {
+	launch SidecarLauncher
+	logger *core.Logger
+
+	mu     sync.RWMutex
+	client *plugin.Client
+	sync   Sync
+	init   *initArgs
+	hooks  []func(context.Context, Sync) error
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewSyncSupervisor(launch SidecarLauncher, logger *core.Logger) *SyncSupervisor {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &SyncSupervisor{
+		launch: launch,
+		logger: logger,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start launches the sidecar and begins watching it for crashes.
+func (s *SyncSupervisor) Start() error {
+	client, _sync, err := s.launch()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.client = client
+	s.sync = _sync
+	s.mu.Unlock()
+
+	go s.watch()
+
+	return nil
+}
+
+// OnRestart registers a hook that runs against the new sidecar after every restart, once Init has been replayed.
+func (s *SyncSupervisor) OnRestart(hook func(context.Context, Sync) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hooks = append(s.hooks, hook)
+}
+
+func (s *SyncSupervisor) watch() {
+	ticker := time.NewTicker(supervisorPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.RLock()
+		exited := s.client == nil || s.client.Exited()
+		s.mu.RUnlock()
+
+		if !exited {
+			continue
+		}
+
+		s.logger.Error("sync sidecar exited, restarting")
+
+		s.mu.Lock()
+		exitedClient := s.client
+		s.client = nil
+		s.sync = nil
+		s.mu.Unlock()
+
+		// Kill releases the exited client's connection and reattach state even though its process is gone
+		if exitedClient != nil {
+			exitedClient.Kill()
+		}
+
+		if !s.restart() {
+			return
+		}
+	}
+}
+
+// restart relaunches the sidecar until it succeeds or the supervisor is stopped. It reports whether the sidecar is running again.
+func (s *SyncSupervisor) restart() bool {
+	backoff := supervisorMinBackoff
+
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-s.ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+
+		err := s.relaunch()
+		if err == nil {
+			s.logger.Info("sync sidecar restarted", zap.Int("attempt", attempt))
+			return true
+		}
+
+		if s.ctx.Err() != nil {
+			return false
+		}
+
+		s.logger.Error("failed to restart sync sidecar", zap.Error(err), zap.Int("attempt", attempt), zap.Duration("backoff", backoff))
+
+		backoff *= 2
+		if backoff > supervisorMaxBackoff {
+			backoff = supervisorMaxBackoff
+		}
+	}
+}
+
+func (s *SyncSupervisor) relaunch() error {
+	client, _sync, err := s.launch()
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	args := s.init
+	hooks := s.hooks
+	s.mu.RUnlock()
+
+	if args != nil {
+		err = _sync.Init(s.ctx, args.logPublicKey, args.nodePrivateKey, args.dataDir)
+		if err != nil {
+			client.Kill()
+			return err
+		}
+	}
+
+	for _, hook := range hooks {
+		err = hook(s.ctx, _sync)
+		if err != nil {
+			client.Kill()
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.ctx.Done():
+		client.Kill()
+		return s.ctx.Err()
+	default:
+	}
+
+	s.client = client
+	s.sync = _sync
+
+	return nil
+}
+
+func (s *SyncSupervisor) current() (Sync, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.sync == nil {
+		return nil, ErrSidecarUnavailable
+	}
+
+	return s.sync, nil
+}
+
+func (s *SyncSupervisor) Init(ctx context.Context, logPublicKey ed25519.PublicKey, nodePrivateKey ed25519.PrivateKey, dataDir string) error {
+	_sync, err := s.current()
+	if err != nil {
+		return err
+	}
+
+	err = _sync.Init(ctx, logPublicKey, nodePrivateKey, dataDir)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.init = &initArgs{
+		logPublicKey:   logPublicKey,
+		nodePrivateKey: nodePrivateKey,
+		dataDir:        dataDir,
+	}
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *SyncSupervisor) Update(ctx context.Context, meta metadata.FileMeta) error {
+	_sync, err := s.current()
+	if err != nil {
+		return err
+	}
+
+	return _sync.Update(ctx, meta)
+}
+
+func (s *SyncSupervisor) Query(ctx context.Context, keys []string) ([]*metadata.FileMeta, error) {
+	_sync, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+
+	return _sync.Query(ctx, keys)
+}
+
+func (s *SyncSupervisor) UpdateNodes(ctx context.Context, nodes []ed25519.PublicKey) error {
+	_sync, err := s.current()
+	if err != nil {
+		return err
+	}
+
+	return _sync.UpdateNodes(ctx, nodes)
+}
+
+func (s *SyncSupervisor) RemoveNode(ctx context.Context, node ed25519.PublicKey) error {
+	_sync, err := s.current()
+	if err != nil {
+		return err
+	}
+
+	return _sync.RemoveNode(ctx, node)
+}
+
+func (s *SyncSupervisor) Remove(ctx context.Context, hash []byte, protocol string) error {
+	_sync, err := s.current()
+	if err != nil {
+		return err
+	}
+
+	return _sync.Remove(ctx, hash, protocol)
+}
+
+// Close stops supervision and kills the sidecar process.
+func (s *SyncSupervisor) Close() error {
+	s.cancel()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client != nil {
+		s.client.Kill()
+		s.client = nil
+		s.sync = nil
+	}
+
+	return nil
+}
@@ -0,0 +1,556 @@
+package synclog
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	bolt "go.etcd.io/bbolt"
+	p2p "go.lumeweb.com/portal-plugin-sync/internal/p2p"
+	"hash"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+const dbFileName = "log.db"
+
+var (
+	bucketMeta    = []byte("meta")
+	bucketEntries = []byte("entries")
+	bucketIndex   = []byte("index")
+	bucketWriters = []byte("writers")
+	bucketTree    = []byte("tree")
+	bucketCores   = []byte("cores")
+
+	metaLogKey = []byte("log_key")
+)
+
+var (
+	ErrNotWriter        = errors.New("node is not an authorized writer")
+	ErrInvalidSignature = errors.New("invalid entry signature")
+	ErrBrokenChain      = errors.New("entry does not follow previous entry")
+	ErrLogKeyMismatch   = errors.New("log key does not match existing log")
+	ErrInvalidLogKey    = errors.New("invalid log key")
+	ErrNoProof          = errors.New("entry has no tree proof")
+)
+
+// Entry is a single signed record in the log. Each entry commits to the hash of the entry before it, so the log can only be appended to. Its value is also block Block of its writer's core, a Hypercore style merkle tree the writer signs on every append.
+type Entry struct {
+	Seq       uint64            `json:"seq"`
+	Key       []byte            `json:"key"`
+	Value     []byte            `json:"value"`
+	Prev      []byte            `json:"prev"`
+	Writer    ed25519.PublicKey `json:"writer"`
+	Signature []byte            `json:"signature"`
+	Block     *uint64           `json:"block,omitempty"`
+}
+
+// core is the signed state of a writer's core: how many blocks it holds and the writer's signature over its tree.
+type core struct {
+	Length    uint64 `json:"length"`
+	Signature []byte `json:"signature"`
+}
+
+// Log is an append-only, ed25519-signed log stored in a local bolt database, together with a lookup index over its entries.
+type Log struct {
+	db     *bolt.DB
+	logKey ed25519.PublicKey
+	key    ed25519.PrivateKey
+	mu     sync.Mutex
+}
+
+// Open opens or creates the log in dir, bound to logKey. A new log starts with key as its only writer.
+func Open(dir string, logKey ed25519.PublicKey, key ed25519.PrivateKey) (*Log, error) {
+	if len(logKey) != ed25519.PublicKeySize {
+		return nil, ErrInvalidLogKey
+	}
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path.Join(dir, dbFileName), 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketMeta, bucketEntries, bucketIndex, bucketWriters, bucketTree, bucketCores} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+
+		meta := tx.Bucket(bucketMeta)
+		existing := meta.Get(metaLogKey)
+		if existing == nil {
+			if err := tx.Bucket(bucketWriters).Put(key.Public().(ed25519.PublicKey), []byte{1}); err != nil {
+				return err
+			}
+
+			return meta.Put(metaLogKey, logKey)
+		}
+
+		if !bytes.Equal(existing, logKey) {
+			return ErrLogKeyMismatch
+		}
+
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &Log{db: db, logKey: logKey, key: key}, nil
+}
+
+// LogKey returns the key that all entries in this log are bound to.
+func (l *Log) LogKey() ed25519.PublicKey {
+	return l.logKey
+}
+
+// Append signs and appends a new entry for key, and indexes it under each of the lookups. Any previous entry this node wrote under the same lookups is superseded.
+func (l *Log) Append(key []byte, value []byte, lookups []string) (*Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	writer := l.key.Public().(ed25519.PublicKey)
+	var entry *Entry
+
+	err := l.db.Update(func(tx *bolt.Tx) error {
+		authorized, err := isWriter(tx, writer)
+		if err != nil {
+			return err
+		}
+
+		if !authorized {
+			return ErrNotWriter
+		}
+
+		entries := tx.Bucket(bucketEntries)
+
+		var prev []byte
+		if k, v := entries.Cursor().Last(); k != nil {
+			var last Entry
+			if err := json.Unmarshal(v, &last); err != nil {
+				return err
+			}
+			prev = entryHash(&last)
+		}
+
+		seq, err := entries.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		entry = &Entry{
+			Seq:    seq,
+			Key:    key,
+			Value:  value,
+			Prev:   prev,
+			Writer: writer,
+		}
+		entry.Signature = ed25519.Sign(l.key, l.signable(entry))
+
+		entry.Block, err = l.appendBlock(tx, writer, value)
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+
+		if err := entries.Put(seqKey(seq), data); err != nil {
+			return err
+		}
+
+		index := tx.Bucket(bucketIndex)
+		for _, lookup := range lookups {
+			if err := index.Put(indexKey(lookup, writer), seqKey(seq)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// Query returns the values of the latest entry from every writer indexed under any of the lookups. Entries that fail verification are skipped.
+func (l *Log) Query(lookups []string) ([][]byte, error) {
+	entries, err := l.QueryEntries(lookups)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([][]byte, 0, len(entries))
+	for _, entry := range entries {
+		values = append(values, entry.Value)
+	}
+
+	return values, nil
+}
+
+// QueryEntries is like Query but returns the verified entries themselves, so callers can see which writer signed each one. Entries from writers that have since been removed are skipped.
+func (l *Log) QueryEntries(lookups []string) ([]*Entry, error) {
+	var result []*Entry
+
+	err := l.db.View(func(tx *bolt.Tx) error {
+		seen := make(map[uint64]struct{})
+		entries := tx.Bucket(bucketEntries)
+		c := tx.Bucket(bucketIndex).Cursor()
+
+		for _, lookup := range lookups {
+			prefix := indexPrefix(lookup)
+			for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+				seq := binary.BigEndian.Uint64(v)
+				if _, ok := seen[seq]; ok {
+					continue
+				}
+				seen[seq] = struct{}{}
+
+				data := entries.Get(v)
+				if data == nil {
+					continue
+				}
+
+				var entry Entry
+				if err := json.Unmarshal(data, &entry); err != nil {
+					return err
+				}
+
+				if l.Verify(&entry) != nil {
+					continue
+				}
+
+				authorized, err := isWriter(tx, entry.Writer)
+				if err != nil {
+					return err
+				}
+
+				if !authorized {
+					continue
+				}
+
+				result = append(result, &entry)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Proof proves that the entry's value is a block of its writer's core, against the writer's latest signed tree.
+func (l *Log) Proof(entry *Entry) (*p2p.BlockProof, error) {
+	if entry.Block == nil {
+		return nil, ErrNoProof
+	}
+
+	var proof *p2p.BlockProof
+
+	err := l.db.View(func(tx *bolt.Tx) error {
+		state, err := getCore(tx, entry.Writer)
+		if err != nil {
+			return err
+		}
+
+		if state == nil || *entry.Block >= state.Length {
+			return ErrNoProof
+		}
+
+		roots, err := getRoots(tx, entry.Writer, state.Length)
+		if err != nil {
+			return err
+		}
+
+		rooted := make(map[uint64]struct{}, len(roots))
+		for _, root := range roots {
+			rooted[root.Index] = struct{}{}
+		}
+
+		var nodes []p2p.TreeNode
+		for index := 2 * *entry.Block; ; index = p2p.FlatParent(index) {
+			if _, ok := rooted[index]; ok {
+				break
+			}
+
+			sibling, err := getNode(tx, entry.Writer, p2p.FlatSibling(index))
+			if err != nil {
+				return err
+			}
+			nodes = append(nodes, *sibling)
+		}
+
+		proof = &p2p.BlockProof{
+			Index:      *entry.Block,
+			Value:      entry.Value,
+			Nodes:      nodes,
+			Roots:      roots,
+			Length:     state.Length,
+			Signatures: []p2p.Signature{{Signer: 0, Signature: state.Signature}},
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return proof, nil
+}
+
+// Verify checks that the entry was signed by its writer for this log.
+func (l *Log) Verify(entry *Entry) error {
+	if len(entry.Writer) != ed25519.PublicKeySize || !ed25519.Verify(entry.Writer, l.signable(entry), entry.Signature) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// VerifyChain walks the full log and checks every signature and back link.
+func (l *Log) VerifyChain() error {
+	return l.db.View(func(tx *bolt.Tx) error {
+		var prev []byte
+
+		return tx.Bucket(bucketEntries).ForEach(func(_, v []byte) error {
+			var entry Entry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return err
+			}
+
+			if err := l.Verify(&entry); err != nil {
+				return err
+			}
+
+			if !bytes.Equal(entry.Prev, prev) {
+				return ErrBrokenChain
+			}
+
+			prev = entryHash(&entry)
+			return nil
+		})
+	})
+}
+
+// SetWriters replaces the set of keys allowed to append to the log.
+func (l *Log) SetWriters(writers []ed25519.PublicKey) error {
+	return l.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(bucketWriters); err != nil {
+			return err
+		}
+
+		bucket, err := tx.CreateBucket(bucketWriters)
+		if err != nil {
+			return err
+		}
+
+		for _, writer := range writers {
+			if err := bucket.Put(writer, []byte{1}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// RemoveWriter revokes a single key's permission to append to the log.
+func (l *Log) RemoveWriter(writer ed25519.PublicKey) error {
+	return l.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketWriters).Delete(writer)
+	})
+}
+
+// Writers returns the keys allowed to append to the log.
+func (l *Log) Writers() ([]ed25519.PublicKey, error) {
+	var writers []ed25519.PublicKey
+
+	err := l.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketWriters).ForEach(func(k, _ []byte) error {
+			writers = append(writers, bytes.Clone(k))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return writers, nil
+}
+
+func (l *Log) Close() error {
+	return l.db.Close()
+}
+
+func (l *Log) signable(entry *Entry) []byte {
+	h := sha256.New()
+	h.Write(l.logKey)
+	h.Write(seqKey(entry.Seq))
+	writeField(h, entry.Key)
+	writeField(h, entry.Value)
+	writeField(h, entry.Prev)
+	h.Write(entry.Writer)
+	return h.Sum(nil)
+}
+
+// appendBlock adds value as the next block of writer's core, filling in every parent it completes, and signs the new tree. It returns the block's index.
+func (l *Log) appendBlock(tx *bolt.Tx, writer ed25519.PublicKey, value []byte) (*uint64, error) {
+	state, err := getCore(tx, writer)
+	if err != nil {
+		return nil, err
+	}
+
+	if state == nil {
+		state = &core{}
+	}
+
+	block := state.Length
+	node := p2p.LeafNode(block, value)
+
+	for {
+		if err := putNode(tx, writer, node); err != nil {
+			return nil, err
+		}
+
+		// Only a right hand node completes its parent, a left hand one waits for its sibling
+		if p2p.FlatSibling(node.Index) > node.Index {
+			break
+		}
+
+		sibling, err := getNode(tx, writer, p2p.FlatSibling(node.Index))
+		if err != nil {
+			return nil, err
+		}
+
+		node = p2p.ParentNode(*sibling, node)
+	}
+
+	state.Length++
+
+	roots, err := getRoots(tx, writer, state.Length)
+	if err != nil {
+		return nil, err
+	}
+
+	state.Signature = p2p.SignTree(l.key, p2p.DEFAULT_NAMESPACE, roots, state.Length, 0)
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Bucket(bucketCores).Put(writer, data); err != nil {
+		return nil, err
+	}
+
+	return &block, nil
+}
+
+func getCore(tx *bolt.Tx, writer ed25519.PublicKey) (*core, error) {
+	data := tx.Bucket(bucketCores).Get(writer)
+	if data == nil {
+		return nil, nil
+	}
+
+	var state core
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+func getRoots(tx *bolt.Tx, writer ed25519.PublicKey, length uint64) ([]p2p.TreeNode, error) {
+	indexes := p2p.FlatFullRoots(length)
+	roots := make([]p2p.TreeNode, 0, len(indexes))
+
+	for _, index := range indexes {
+		root, err := getNode(tx, writer, index)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, *root)
+	}
+
+	return roots, nil
+}
+
+// Tree nodes are stored under the writer and their flat index, as their size followed by their hash.
+func getNode(tx *bolt.Tx, writer ed25519.PublicKey, index uint64) (*p2p.TreeNode, error) {
+	data := tx.Bucket(bucketTree).Get(nodeKey(writer, index))
+	if len(data) != 8+32 {
+		return nil, ErrNoProof
+	}
+
+	return &p2p.TreeNode{
+		Index: index,
+		Size:  binary.BigEndian.Uint64(data[:8]),
+		Hash:  bytes.Clone(data[8:]),
+	}, nil
+}
+
+func putNode(tx *bolt.Tx, writer ed25519.PublicKey, node p2p.TreeNode) error {
+	data := binary.BigEndian.AppendUint64(nil, node.Size)
+	data = append(data, node.Hash...)
+
+	return tx.Bucket(bucketTree).Put(nodeKey(writer, node.Index), data)
+}
+
+func nodeKey(writer ed25519.PublicKey, index uint64) []byte {
+	return append(bytes.Clone(writer), seqKey(index)...)
+}
+
+// isWriter reports whether writer may append.
+func isWriter(tx *bolt.Tx, writer ed25519.PublicKey) (bool, error) {
+	return tx.Bucket(bucketWriters).Get(writer) != nil, nil
+}
+
+func entryHash(entry *Entry) []byte {
+	h := sha256.New()
+	h.Write(seqKey(entry.Seq))
+	writeField(h, entry.Key)
+	writeField(h, entry.Value)
+	writeField(h, entry.Prev)
+	h.Write(entry.Writer)
+	h.Write(entry.Signature)
+	return h.Sum(nil)
+}
+
+func writeField(h hash.Hash, data []byte) {
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(data)))
+	h.Write(length[:])
+	h.Write(data)
+}
+
+func seqKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+func indexPrefix(lookup string) []byte {
+	return append([]byte(lookup), 0)
+}
+
+func indexKey(lookup string, writer ed25519.PublicKey) []byte {
+	return append(indexPrefix(lookup), writer...)
+}
@@ -0,0 +1,250 @@
+package synclog
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	p2p "go.lumeweb.com/portal-plugin-sync/internal/p2p"
+	"testing"
+)
+
+func newKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func openLog(t *testing.T, dir string, key ed25519.PrivateKey) *Log {
+	t.Helper()
+
+	log, err := Open(dir, bytes.Repeat([]byte{1}, ed25519.PublicKeySize), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = log.Close()
+	})
+
+	return log
+}
+
+func TestAppendQuery(t *testing.T) {
+	log := openLog(t, t.TempDir(), newKey(t))
+
+	if _, err := log.Append([]byte("a"), []byte("first"), []string{"a", "alias"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := log.Append([]byte("b"), []byte("other"), []string{"b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := log.Query([]string{"alias"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != 1 || string(values[0]) != "first" {
+		t.Fatalf("unexpected values %q", values)
+	}
+
+	if _, err := log.Append([]byte("a"), []byte("second"), []string{"a", "alias"}); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err = log.Query([]string{"a", "alias"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != 1 || string(values[0]) != "second" {
+		t.Fatalf("expected only the latest entry, got %q", values)
+	}
+
+	if err := log.VerifyChain(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQuerySkipsRemovedWriters(t *testing.T) {
+	key := newKey(t)
+	self := key.Public().(ed25519.PublicKey)
+	other := newKey(t).Public().(ed25519.PublicKey)
+	log := openLog(t, t.TempDir(), key)
+
+	if err := log.SetWriters([]ed25519.PublicKey{self, other}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := log.Append([]byte("a"), []byte("value"), []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := log.RemoveWriter(self); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := log.QueryEntries([]string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("expected entries from removed writers to be skipped, got %d", len(entries))
+	}
+
+	if _, err := log.Append([]byte("a"), []byte("value"), []string{"a"}); !errors.Is(err, ErrNotWriter) {
+		t.Fatalf("expected ErrNotWriter, got %v", err)
+	}
+}
+
+func TestOpenLogKey(t *testing.T) {
+	dir := t.TempDir()
+	key := newKey(t)
+
+	if _, err := Open(dir, nil, key); !errors.Is(err, ErrInvalidLogKey) {
+		t.Fatalf("expected ErrInvalidLogKey, got %v", err)
+	}
+
+	log, err := Open(dir, bytes.Repeat([]byte{1}, ed25519.PublicKeySize), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(dir, bytes.Repeat([]byte{2}, ed25519.PublicKeySize), key); !errors.Is(err, ErrLogKeyMismatch) {
+		t.Fatalf("expected ErrLogKeyMismatch, got %v", err)
+	}
+}
+
+func TestVerifyRejectsTamperedEntry(t *testing.T) {
+	log := openLog(t, t.TempDir(), newKey(t))
+
+	entry, err := log.Append([]byte("a"), []byte("value"), []string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := log.Verify(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	entry.Value = []byte("tampered")
+
+	if err := log.Verify(entry); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func TestProof(t *testing.T) {
+	key := newKey(t)
+	writer := key.Public().(ed25519.PublicKey)
+	log := openLog(t, t.TempDir(), key)
+
+	var entries []*Entry
+	for i := 0; i < 7; i++ {
+		entry, err := log.Append([]byte{byte(i)}, []byte{byte(i), 'v'}, []string{string(rune('a' + i))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+
+		// Every earlier entry stays provable against the latest signed tree
+		for _, earlier := range entries {
+			proof, err := log.Proof(earlier)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if proof.Length != uint64(len(entries)) {
+				t.Fatalf("proof against length %d, want %d", proof.Length, len(entries))
+			}
+
+			if err := p2p.VerifyBlock(p2p.WriterManifest(writer), proof); err != nil {
+				t.Fatalf("block %d of %d: %v", *earlier.Block, len(entries), err)
+			}
+		}
+	}
+
+	queried, err := log.QueryEntries([]string{"c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(queried) != 1 || queried[0].Block == nil || *queried[0].Block != 2 {
+		t.Fatalf("expected the third block, got %+v", queried)
+	}
+
+	proof, err := log.Proof(queried[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof.Value = []byte("tampered")
+	if err := p2p.VerifyBlock(p2p.WriterManifest(writer), proof); !errors.Is(err, p2p.ErrInvalidProof) {
+		t.Fatalf("expected ErrInvalidProof, got %v", err)
+	}
+
+	other := newKey(t).Public().(ed25519.PublicKey)
+	proof, err = log.Proof(queried[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p2p.VerifyBlock(p2p.WriterManifest(other), proof); !errors.Is(err, p2p.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func TestProofWithoutBlock(t *testing.T) {
+	log := openLog(t, t.TempDir(), newKey(t))
+
+	entry, err := log.Append([]byte("a"), []byte("value"), []string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry.Block = nil
+	if _, err := log.Proof(entry); !errors.Is(err, ErrNoProof) {
+		t.Fatalf("expected ErrNoProof, got %v", err)
+	}
+}
+
+func TestOpenSeedsOnlyLocalWriter(t *testing.T) {
+	dir := t.TempDir()
+	logKey := bytes.Repeat([]byte{1}, ed25519.PublicKeySize)
+	key := newKey(t)
+
+	log, err := Open(dir, logKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writers, err := log.Writers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(writers) != 1 || !bytes.Equal(writers[0], key.Public().(ed25519.PublicKey)) {
+		t.Fatalf("expected only the local key as writer, got %d writers", len(writers))
+	}
+
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	other := openLog(t, dir, newKey(t))
+
+	if _, err := other.Append([]byte("a"), []byte("value"), []string{"a"}); !errors.Is(err, ErrNotWriter) {
+		t.Fatalf("expected ErrNotWriter for a key the log was not created with, got %v", err)
+	}
+}
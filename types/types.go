@@ -1,9 +1,155 @@
 package types
 
-import "go.lumeweb.com/portal/core"
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"go.lumeweb.com/portal/core"
+	"io"
+	"time"
+)
 
 const SYNC_SERVICE = "sync"
 
+type ImportStatus string
+
+const (
+	ImportStatusQueued    ImportStatus = "queued"
+	ImportStatusVerifying ImportStatus = "verifying"
+	ImportStatusVerified  ImportStatus = "verified"
+	ImportStatusUploading ImportStatus = "uploading"
+	ImportStatusCompleted ImportStatus = "completed"
+	ImportStatusFailed    ImportStatus = "failed"
+)
+
+type ImportRejectReason string
+
+const (
+	ImportRejectInvalidIdentifier ImportRejectReason = "invalid_identifier"
+	ImportRejectNotFound          ImportRejectReason = "not_found"
+	ImportRejectAlreadyExists     ImportRejectReason = "already_exists"
+	ImportRejectNoShards          ImportRejectReason = "no_shards"
+	ImportRejectDuplicate         ImportRejectReason = "duplicate"
+	ImportRejectDenied            ImportRejectReason = "denied"
+	ImportRejectQuotaExceeded     ImportRejectReason = "quota_exceeded"
+	ImportRejectTooLarge          ImportRejectReason = "too_large"
+	ImportRejectUnproven          ImportRejectReason = "unproven"
+	ImportRejectError             ImportRejectReason = "error"
+)
+
+var (
+	ErrImportNotFound        = errors.New("import not found")
+	ErrInvalidIdentifier     = errors.New("invalid object")
+	ErrObjectNotFound        = errors.New("object not found")
+	ErrObjectExists          = errors.New("object already exists")
+	ErrObjectNoShards        = errors.New("object has at-least one slab with no shards")
+	ErrDeadLetterNotFound    = errors.New("dead letter not found")
+	ErrObjectDenied          = errors.New("object is on the denylist")
+	ErrDenylistEntryNotFound = errors.New("denylist entry not found")
+	ErrImportQuotaExceeded   = errors.New("import quota exceeded")
+	ErrObjectTooLarge        = errors.New("object exceeds the maximum import size")
+	ErrObjectUnproven        = errors.New("object is not proven to come from an authorized writer")
+	ErrClusterDisabled       = errors.New("clustering is not enabled")
+	ErrSyncNodeNotFound      = errors.New("sync node not found")
+)
+
+var (
+	ErrImportLimitConcurrent = fmt.Errorf("%w: too many imports in progress", ErrImportQuotaExceeded)
+	ErrImportLimitDaily      = fmt.Errorf("%w: daily import limit reached", ErrImportQuotaExceeded)
+	ErrImportLimitBytes      = fmt.Errorf("%w: imported storage limit reached", ErrImportQuotaExceeded)
+)
+
+type ImportInfo struct {
+	ID        uint
+	UserID    uint
+	Hash      []byte
+	Object    string
+	Protocol  string
+	Size      uint64
+	Status    ImportStatus
+	Error     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ImportResult is the outcome of one object in a batch import. ImportID is set when the object was accepted, Reason when it was rejected.
+type ImportResult struct {
+	Object   string
+	ImportID uint
+	Reason   ImportRejectReason
+	Error    string
+}
+
+func (r ImportResult) Accepted() bool {
+	return r.ImportID != 0
+}
+
+// ImportEstimate is what importing an object would cost. DownloadBytes is what the renter fetches from hosts, StorageBytes what the import adds to the user's storage.
+type ImportEstimate struct {
+	Object        string
+	Hash          []byte
+	Protocol      string
+	Size          uint64
+	DownloadBytes uint64
+	StorageBytes  uint64
+}
+
+// ObjectInfo describes one publisher's copy of an object in the sync log, without its encryption keys.
+type ObjectInfo struct {
+	Hash      []byte
+	Protocol  string
+	Size      uint64
+	SlabCount int
+	Health    float64
+	MinShards uint8
+	Shards    int
+	Aliases   []string
+}
+
+// PublishRetryInfo describes an upload whose publish to the sync log keeps failing.
+type PublishRetryInfo struct {
+	ID        uint
+	UploadID  uint
+	Hash      []byte
+	Protocol  string
+	Attempts  int
+	LastError string
+	UpdatedAt time.Time
+}
+
+// DenylistEntry is a hash that operators have blocked from being imported or published.
+type DenylistEntry struct {
+	Hash      []byte
+	Reason    string
+	CreatedAt time.Time
+}
+
+// SyncNodeInfo describes a node registered in the cluster's sync writer set. LeaseTTL is the time left on its etcd registration. Bootstrap marks the node the log key is derived from, Indexer the nodes currently indexing the log.
+type SyncNodeInfo struct {
+	NodeID    string
+	PublicKey ed25519.PublicKey
+	LeaseTTL  time.Duration
+	Bootstrap bool
+	Indexer   bool
+	Self      bool
+}
+
+// LogView describes the indexers signing the log view. Writable is false when fewer than Quorum of them are registered, in which case the view cannot advance until enough return.
+type LogView struct {
+	Name     string
+	Key      ed25519.PublicKey
+	Quorum   int
+	Signers  []LogSigner
+	Writable bool
+}
+
+type LogSigner struct {
+	NodeID    string
+	PublicKey ed25519.PublicKey
+	Live      bool
+}
+
 type SyncProtocol interface {
 	Name() string
 	EncodeFileName([]byte) string
@@ -12,9 +158,30 @@ type SyncProtocol interface {
 	StorageProtocol() core.StorageProtocol
 }
 type SyncService interface {
-	Update(upload core.UploadMetadata) error
+	Update(ctx context.Context, upload core.UploadMetadata) error
+	Remove(ctx context.Context, hash []byte) error
+	RemoveDeleted(ctx context.Context) error
 	LogKey() []byte
-	Import(object string, uploaderID uint64) error
+	DiscoveryKey() []byte
+	Import(ctx context.Context, object string, uploaderID uint64) (uint, error)
+	EstimateImport(ctx context.Context, object string, uploaderID uint64) (*ImportEstimate, error)
+	ImportBatch(ctx context.Context, objects []string, uploaderID uint64) ([]ImportResult, error)
+	Lookup(ctx context.Context, object string) ([]ObjectInfo, error)
+	GetImport(ctx context.Context, id uint) (*ImportInfo, error)
+	ListImports(ctx context.Context, userID uint64) ([]ImportInfo, error)
+	RetryPublishes(ctx context.Context) error
+	ListDeadLetters(ctx context.Context) ([]PublishRetryInfo, error)
+	RequeueDeadLetter(ctx context.Context, id uint) error
+	IsDenied(ctx context.Context, hash []byte) (bool, error)
+	Deny(ctx context.Context, hash []byte, reason string) error
+	Allow(ctx context.Context, hash []byte) error
+	ListDenied(ctx context.Context) ([]DenylistEntry, error)
+	ImportDenylist(ctx context.Context, r io.Reader) (int, error)
+	ListSyncNodes(ctx context.Context) ([]SyncNodeInfo, error)
+	RemoveSyncNode(ctx context.Context, nodeID string) error
+	PushSyncNodes(ctx context.Context) error
+	LogView(ctx context.Context) (*LogView, error)
+	IsAdmin(userID uint64) bool
 	Enabled() bool
 
 	core.Service